@@ -0,0 +1,83 @@
+package onnxruntime
+
+import (
+	"context"
+
+	"github.com/c3sr/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"gorgonia.org/tensor"
+)
+
+// Future is a prediction started by PredictAsync
+type Future struct {
+	done   chan struct{}
+	result *Result
+	err    error
+}
+
+// PredictAsync copies the inputs for onnxruntime and starts the run on another goroutine, so that the caller
+// can prepare the next inputs while the model runs. The inputs can be changed as soon as PredictAsync returns.
+// The prediction is stopped when ctx is done, the future then fails with the error of ctx.
+func (p *Predictor) PredictAsync(ctx context.Context, inputs []tensor.Tensor) *Future {
+	// the span is started by the caller, so that the spans of the prediction are its children
+	// even when the span of the caller is finished first
+	span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_predict_async")
+
+	f := &Future{
+		done: make(chan struct{}),
+	}
+
+	values := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		values[i] = input
+	}
+	var res *Result
+	var spanOptions []opentracing.StartSpanOption
+	func() {
+		defer recoverError(&f.err)
+		res, spanOptions, f.err = p.prepare(values)
+	}()
+	if f.err != nil {
+		close(f.done)
+		span.Finish()
+		return f
+	}
+
+	go func() {
+		defer close(f.done)
+		defer span.Finish()
+		err := func() (err error) {
+			defer recoverError(&err)
+			return res.run(ctx, spanOptions...)
+		}()
+		if err != nil {
+			res.Close()
+			f.err = err
+			return
+		}
+		f.result = res
+	}()
+	return f
+}
+
+// Done is closed once the prediction is over
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Result waits for the prediction and returns its result, which has to be closed by the caller
+func (f *Future) Result() (*Result, error) {
+	<-f.done
+	return f.result, f.err
+}
+
+// Wait waits for the prediction or for ctx to be done, in which case the prediction keeps going
+// and its result can still be taken with Result
+func (f *Future) Wait(ctx context.Context) (*Result, error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,78 @@
+package onnxruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestPredictAsync(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	futures := make([]*Future, 8)
+	for i := range futures {
+		x := float32(i)
+		futures[i] = predictor.PredictAsync(ctx, []gotensor.Tensor{
+			gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{x, x, x, x}), gotensor.WithShape(4)),
+		})
+	}
+
+	for i, future := range futures {
+		x := float32(i)
+		result, err := future.Wait(ctx)
+		if err != nil {
+			t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+		}
+		outputs, err := result.ReadPredictionOutput(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []float32{2 * x, 2 * x, 2 * x, 2 * x}, outputs[0].Data())
+		result.Close()
+
+		// the result stays available once the future is done
+		_, err = future.Result()
+		assert.NoError(t, err)
+	}
+}
+
+func TestPredictAsyncChangedInputs(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	backing := []float32{1, 2, 3, 4}
+	future := predictor.PredictAsync(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(backing), gotensor.WithShape(4)),
+	})
+	// the inputs are copied before PredictAsync returns
+	for i := range backing {
+		backing[i] = -1
+	}
+
+	result, err := future.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+	outputs, err := result.ReadPredictionOutput(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{2, 4, 6, 8}, outputs[0].Data())
+}
+
+func TestPredictAsyncCanceled(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	future := predictor.PredictAsync(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)),
+	})
+	<-future.Done()
+	result, err := future.Result()
+	assert.Nil(t, result)
+	assert.Equal(t, context.Canceled, err)
+}
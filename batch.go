@@ -0,0 +1,141 @@
+package onnxruntime
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+/* Description: Check whether a shape has batchSize as its first, batch, dimension */
+func isBatched(shape []int64, batchSize int) bool {
+	return len(shape) > 0 && shape[0] == int64(batchSize)
+}
+
+func sameShape(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SplitBatch splits a value along its first dimension into batchSize per-sample values,
+// which share the slice of v. A value whose first dimension is not batchSize, e.g. a scalar,
+// has no batch dimension and is returned as it is for every sample.
+// The shape alone cannot tell a batch from a value that happens to be batchSize long,
+// Result.ReadPredictionOutputSamples uses the shapes declared by the model instead.
+func SplitBatch(v Value, batchSize int) ([]Value, error) {
+	return splitBatch(v, batchSize, isBatched(v.Shape, batchSize))
+}
+
+/* Description: Split v into batchSize samples when batched, else share it with every sample */
+func splitBatch(v Value, batchSize int, batched bool) ([]Value, error) {
+	if batchSize < 1 {
+		return nil, errors.Errorf("invalid batch size %d", batchSize)
+	}
+	if err := v.check(); err != nil {
+		return nil, err
+	}
+
+	if batched && !isBatched(v.Shape, batchSize) {
+		return nil, errors.Errorf("shape %v has no batch dimension of size %d", v.Shape, batchSize)
+	}
+
+	res := make([]Value, batchSize)
+	if !batched {
+		for i := range res {
+			res[i] = v
+		}
+		return res, nil
+	}
+
+	data := reflect.ValueOf(v.Data)
+	sampleShape := v.Shape[1:]
+	sampleLength := getFlattenedLength(sampleShape)
+	for i := range res {
+		res[i] = Value{
+			Data:  data.Slice3(i*sampleLength, (i+1)*sampleLength, (i+1)*sampleLength).Interface(),
+			Shape: append([]int64{}, sampleShape...),
+		}
+	}
+	return res, nil
+}
+
+// StackBatch stacks per-sample values of the same type and shape into a batch,
+// the result has a new first dimension holding the samples and its own slice
+func StackBatch(samples []Value) (Value, error) {
+	if len(samples) == 0 {
+		return Value{}, errors.New("no samples to stack")
+	}
+	for i, sample := range samples {
+		if err := sample.check(); err != nil {
+			return Value{}, errors.Wrapf(err, "invalid sample %d", i)
+		}
+		if reflect.TypeOf(sample.Data) != reflect.TypeOf(samples[0].Data) {
+			return Value{}, errors.Errorf("sample %d holds %T while sample 0 holds %T", i, sample.Data, samples[0].Data)
+		}
+		if !sameShape(sample.Shape, samples[0].Shape) {
+			return Value{}, errors.Errorf("sample %d has the shape %v while sample 0 has the shape %v", i, sample.Shape, samples[0].Shape)
+		}
+	}
+
+	sampleLength := samples[0].Len()
+	data := reflect.MakeSlice(reflect.TypeOf(samples[0].Data), len(samples)*sampleLength, len(samples)*sampleLength)
+	for i, sample := range samples {
+		reflect.Copy(data.Slice(i*sampleLength, (i+1)*sampleLength), reflect.ValueOf(sample.Data))
+	}
+
+	return Value{
+		Data:  data.Interface(),
+		Shape: append([]int64{int64(len(samples))}, samples[0].Shape...),
+	}, nil
+}
+
+// SplitBatchTensor is SplitBatch for gorgonia tensors, dense tensors share their backing slice with the samples
+func SplitBatchTensor(t tensor.Tensor, batchSize int) ([]tensor.Tensor, error) {
+	v, err := tensorToValue(t)
+	if err != nil {
+		return nil, err
+	}
+	return splitBatchTensor(t, v, batchSize, isBatched(v.Shape, batchSize))
+}
+
+/* Description: splitBatch for a gorgonia tensor t and its value v */
+func splitBatchTensor(t tensor.Tensor, v Value, batchSize int, batched bool) ([]tensor.Tensor, error) {
+	values, err := splitBatch(v, batchSize, batched)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]tensor.Tensor, len(values))
+	for i, value := range values {
+		if batched {
+			res[i] = valueToTensor(value)
+		} else {
+			res[i] = t
+		}
+	}
+	return res, nil
+}
+
+// StackBatchTensors is StackBatch for gorgonia tensors
+func StackBatchTensors(samples []tensor.Tensor) (tensor.Tensor, error) {
+	values := make([]Value, len(samples))
+	for i, sample := range samples {
+		v, err := tensorToValue(sample)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid sample %d", i)
+		}
+		values[i] = v
+	}
+
+	v, err := StackBatch(values)
+	if err != nil {
+		return nil, err
+	}
+	return valueToTensor(v), nil
+}
@@ -0,0 +1,145 @@
+package onnxruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/c3sr/dlframework/framework/options"
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestSplitBatch(t *testing.T) {
+	v := Value{Data: []int32{1, 2, 3, 4, 5, 6}, Shape: []int64{3, 2}}
+	samples, err := SplitBatch(v, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []Value{
+		{Data: []int32{1, 2}, Shape: []int64{2}},
+		{Data: []int32{3, 4}, Shape: []int64{2}},
+		{Data: []int32{5, 6}, Shape: []int64{2}},
+	}, samples)
+
+	// the samples share the data of the batch
+	samples[1].Data.([]int32)[0] = 30
+	assert.Equal(t, int32(30), v.Data.([]int32)[2])
+
+	// without a batch dimension every sample gets the whole value
+	scalar := Value{Data: []float32{0.5}}
+	samples, err = SplitBatch(scalar, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []Value{scalar, scalar}, samples)
+
+	_, err = SplitBatch(v, 0)
+	assert.Error(t, err)
+}
+
+func TestStackBatch(t *testing.T) {
+	batch, err := StackBatch([]Value{
+		{Data: []float32{1, 2}, Shape: []int64{2}},
+		{Data: []float32{3, 4}, Shape: []int64{2}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Value{Data: []float32{1, 2, 3, 4}, Shape: []int64{2, 2}}, batch)
+
+	samples, err := SplitBatch(batch, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{3, 4}, samples[1].Data)
+
+	_, err = StackBatch([]Value{
+		{Data: []float32{1, 2}, Shape: []int64{2}},
+		{Data: []float32{3, 4}, Shape: []int64{1, 2}},
+	})
+	assert.Error(t, err)
+	_, err = StackBatch([]Value{
+		{Data: []float32{1}, Shape: []int64{1}},
+		{Data: []float64{3}, Shape: []int64{1}},
+	})
+	assert.Error(t, err)
+	_, err = StackBatch(nil)
+	assert.Error(t, err)
+}
+
+func TestStackAndSplitBatchTensors(t *testing.T) {
+	batch, err := StackBatchTensors([]gotensor.Tensor{
+		gotensor.New(gotensor.WithBacking([]int64{1, 2, 3}), gotensor.WithShape(3)),
+		gotensor.New(gotensor.WithBacking([]int64{4, 5, 6}), gotensor.WithShape(3)),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, gotensor.Shape{2, 3}, batch.Shape())
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6}, batch.Data())
+
+	samples, err := SplitBatchTensor(batch, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, gotensor.Shape{3}, samples[1].Shape())
+	assert.Equal(t, []int64{4, 5, 6}, samples[1].Data())
+}
+
+func TestReadPredictionOutputSamples(t *testing.T) {
+	// y keeps the batch dimension of x while the sum s has none
+	model := writeTestModel(t,
+		[]testNode{
+			{opType: "Identity", inputs: []string{"x"}, outputs: []string{"y"}},
+			{opType: "ReduceSum", inputs: []string{"x"}, outputs: []string{"s"}, attrs: []protoMessage{intAttr("keepdims", 0)}},
+		},
+		[]testValue{{"x", tensorType(onnxFloat, -1, 2)}},
+		[]testValue{{"y", tensorType(onnxFloat, -1, 2)}, {"s", tensorType(onnxFloat)}},
+	)
+
+	predictor := newCPUPredictor(t, model, options.BatchSize(3))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.WithBacking([]float32{1, 2, 3, 4, 5, 6}), gotensor.WithShape(3, 2)),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	samples, err := result.ReadPredictionOutputSamples(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+	assert.Len(t, samples, 3)
+	for i, outputs := range samples {
+		assert.Equal(t, []float32{float32(2*i + 1), float32(2*i + 2)}, outputs[0].Data())
+		assert.Equal(t, float32(21), outputs[1].Data())
+	}
+}
+
+func TestReadPredictionOutputSamplesUnbatched(t *testing.T) {
+	// the table t is as long as the batch but is declared without a batch dimension
+	model := writeTestModel(t,
+		[]testNode{
+			{opType: "Identity", inputs: []string{"x"}, outputs: []string{"y"}},
+			{opType: "Identity", inputs: []string{"w"}, outputs: []string{"t"}},
+		},
+		[]testValue{{"x", tensorType(onnxFloat, -1, 2)}, {"w", tensorType(onnxFloat, 3, 2)}},
+		[]testValue{{"y", tensorType(onnxFloat, -1, 2)}, {"t", tensorType(onnxFloat, 3, 2)}},
+	)
+
+	predictor := newCPUPredictor(t, model, options.BatchSize(3))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.WithBacking([]float32{1, 2, 3, 4, 5, 6}), gotensor.WithShape(3, 2)),
+		gotensor.New(gotensor.WithBacking([]float32{10, 20, 30, 40, 50, 60}), gotensor.WithShape(3, 2)),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	samples, err := result.ReadPredictionOutputSamples(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+	assert.Len(t, samples, 3)
+	for i, outputs := range samples {
+		assert.Equal(t, []float32{float32(2*i + 1), float32(2*i + 2)}, outputs[0].Data())
+		assert.Equal(t, gotensor.Shape{3, 2}, outputs[1].Shape())
+		assert.Equal(t, []float32{10, 20, 30, 40, 50, 60}, outputs[1].Data())
+	}
+}
@@ -0,0 +1,56 @@
+package onnxruntime
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+
+	"gorgonia.org/tensor"
+)
+
+// BFloat16 is a brain floating point number, the upper 16 bits of an IEEE 754 float32.
+// It is the Go element type used for ONNX_TENSOR_ELEMENT_DATA_TYPE_BFLOAT16 tensors.
+type BFloat16 uint16
+
+// BFloat16Dtype is the gorgonia tensor type for tensors backed by []BFloat16
+var BFloat16Dtype = tensor.Dtype{Type: reflect.TypeOf(BFloat16(0))}
+
+/* Description: Convert a float32 to the nearest bfloat16 number (round to nearest even)
+ *              NaNs are kept quiet so that truncation can not turn them into infinities
+ */
+func NewBFloat16(f float32) BFloat16 {
+	bits := math.Float32bits(f)
+	if bits&0x7fffffff > 0x7f800000 {
+		return BFloat16(bits>>16 | 0x0040)
+	}
+	bits += 0x7fff + (bits>>16)&1
+	return BFloat16(bits >> 16)
+}
+
+// Float32 returns the float32 value of the bfloat16 number, the conversion is exact
+func (b BFloat16) Float32() float32 {
+	return math.Float32frombits(uint32(b) << 16)
+}
+
+// String formats the number as its float32 value
+func (b BFloat16) String() string {
+	return strconv.FormatFloat(float64(b.Float32()), 'g', -1, 32)
+}
+
+// Float32ToBFloat16 converts a float32 slice into a newly allocated BFloat16 slice
+func Float32ToBFloat16(data []float32) []BFloat16 {
+	res := make([]BFloat16, len(data))
+	for i, d := range data {
+		res[i] = NewBFloat16(d)
+	}
+	return res
+}
+
+// BFloat16ToFloat32 converts a BFloat16 slice into a newly allocated float32 slice
+func BFloat16ToFloat32(data []BFloat16) []float32 {
+	res := make([]float32, len(data))
+	for i, d := range data {
+		res[i] = d.Float32()
+	}
+	return res
+}
@@ -0,0 +1,52 @@
+package onnxruntime
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestBFloat16Conversion(t *testing.T) {
+	cases := []struct {
+		f    float32
+		bits BFloat16
+	}{
+		{0, 0x0000},
+		{1, 0x3f80},
+		{-2, 0xc000},
+		{0.5, 0x3f00},
+		{3.3895314e+38, 0x7f7f}, // largest finite
+		{float32(math.Inf(1)), 0x7f80},
+		{float32(math.Inf(-1)), 0xff80},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.bits, NewBFloat16(c.f), "converting %v", c.f)
+		assert.Equal(t, c.f, c.bits.Float32(), "converting %#04x", uint16(c.bits))
+	}
+
+	// rounding
+	assert.Equal(t, BFloat16(0x3f80), NewBFloat16(1+1.0/256))  // ties to even, rounds down
+	assert.Equal(t, BFloat16(0x3f82), NewBFloat16(1+3.0/256))  // ties to even, rounds up
+	assert.Equal(t, BFloat16(0x3f81), NewBFloat16(1+1.25/128)) // below the tie, rounds down
+	assert.True(t, math.IsNaN(float64(NewBFloat16(math.Float32frombits(0x7f800001)).Float32())))
+
+	data := []float32{0, 1, -1.5, 1024, 0.25}
+	assert.Equal(t, data, BFloat16ToFloat32(Float32ToBFloat16(data)))
+}
+
+func TestBFloat16Identity(t *testing.T) {
+	data := Float32ToBFloat16([]float32{0, 1, -2.5, 0.1, 3e38, -1e-30})
+
+	predictor := newCPUPredictor(t, identityModel(t, onnxBFloat16))
+	defer predictor.Close()
+
+	outputs := runTestPredictor(t, predictor, gotensor.New(
+		gotensor.Of(BFloat16Dtype),
+		gotensor.WithBacking(data),
+		gotensor.WithShape(len(data)),
+	))
+	assert.Equal(t, BFloat16Dtype, outputs[0].Dtype())
+	assert.Equal(t, data, outputs[0].Data().([]BFloat16))
+}
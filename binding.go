@@ -0,0 +1,208 @@
+package onnxruntime
+
+// #include <stdlib.h>
+// #include "cbits/predictor.hpp"
+import "C"
+import (
+	"context"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Binding ties tensors to the inputs and the outputs of a predictor by name, so that the model can be run
+// repeatedly without any allocation: Run reads the bound input tensors and writes into the bound output tensors.
+// The output tensors must have the type and the shape of the outputs produced by the model.
+// onnxruntime runs on memory of its own allocated when binding, Run copies the tensors into it and back.
+// A binding replaces Predict and reading the Result for the predictor it was created from.
+type Binding struct {
+	predictor *Predictor
+	session   *session
+	// mu guards ctx, so that closing the binding, e.g. with its predictor, waits for a running Run
+	mu      sync.Mutex
+	ctx     C.ORT_BindingContext
+	inputs  map[string]boundTensor
+	outputs map[string]boundTensor
+}
+
+// boundTensor pairs a bound tensor with the memory owned by onnxruntime which is bound in its place,
+// C++ can not keep pointers to Go memory so Run copies the data between them
+type boundTensor struct {
+	tensor *tensor.Dense
+	data   []byte
+	cData  []byte
+}
+
+// NewBinding creates a binding for the predictor, it is closed at the latest when the predictor is closed.
+// A binding keeps running the model it was created with when the predictor is reloaded.
+func (p *Predictor) NewBinding() (_ *Binding, err error) {
+	defer recoverError(&err)
+
+	s, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	var cErr C.ORT_Error
+	ctx := C.ORT_NewBinding(s.ctx, &cErr)
+	if err := takeError(&cErr); err != nil {
+		s.release()
+		return nil, err
+	}
+
+	b := &Binding{
+		predictor: p,
+		session:   s,
+		ctx:       ctx,
+		inputs:    map[string]boundTensor{},
+		outputs:   map[string]boundTensor{},
+	}
+	p.mu.Lock()
+	if p.session == nil {
+		p.mu.Unlock()
+		b.Close()
+		return nil, errors.New("predictor is closed")
+	}
+	if p.bindings == nil {
+		p.bindings = map[*Binding]struct{}{}
+	}
+	p.bindings[b] = struct{}{}
+	p.mu.Unlock()
+
+	return b, nil
+}
+
+// BindInput binds the tensor to the input called name, later changes to the tensor data are seen by Run
+func (b *Binding) BindInput(name string, ten tensor.Tensor) error {
+	return b.bind(name, ten, true)
+}
+
+// BindOutput binds the tensor to the output called name, Run writes the output into the tensor data
+func (b *Binding) BindOutput(name string, ten tensor.Tensor) error {
+	return b.bind(name, ten, false)
+}
+
+func (b *Binding) bind(name string, ten tensor.Tensor, input bool) (err error) {
+	defer recoverError(&err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ctx == nil {
+		return errors.New("binding is closed")
+	}
+
+	// the memory of the tensor itself is bound, so it can not be a copy
+	dense, ok := ten.(*tensor.Dense)
+	if !ok || !isRowMajor(dense) {
+		return errors.New("expecting a contiguous dense tensor")
+	}
+	if dense.Dtype() == tensor.String {
+		return errors.New("string tensors can not be bound")
+	}
+
+	v, err := tensorToValue(dense)
+	if err != nil {
+		return err
+	}
+	dataType, err := valueDataType(v)
+	if err != nil {
+		return err
+	}
+
+	shape := v.Shape
+	shapePtr := cShape(shape)
+
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	var cErr C.ORT_Error
+	var cData unsafe.Pointer
+	if input {
+		cData = C.ORT_BindingBindInput(b.ctx, cName, shapePtr, C.int(len(shape)), dataType, &cErr)
+	} else {
+		cData = C.ORT_BindingBindOutput(b.ctx, cName, shapePtr, C.int(len(shape)), dataType, &cErr)
+	}
+
+	runtime.KeepAlive(shape)
+
+	if err := takeError(&cErr); err != nil {
+		return err
+	}
+
+	// tensorToValue copies the element of a scalar, the data has to come from the tensor
+	bound := boundTensor{tensor: dense}
+	if size := v.Len() * int(dense.Dtype().Size()); size > 0 {
+		bound.data = byteSlice(dense.Pointer(), size)
+		bound.cData = byteSlice(cData, size)
+	}
+
+	if input {
+		b.inputs[name] = bound
+	} else {
+		b.outputs[name] = bound
+	}
+	return nil
+}
+
+// Run runs the model on the bound inputs, the outputs are written into the bound output tensors.
+// A binding is not safe for concurrent use, create one binding per goroutine instead.
+func (b *Binding) Run(ctx context.Context) (err error) {
+	defer recoverError(&err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ctx == nil {
+		return errors.New("binding is closed")
+	}
+
+	for _, bound := range b.inputs {
+		copy(bound.cData, bound.data)
+	}
+
+	err = b.session.traceRun(ctx, func() error {
+		var cErr C.ORT_Error
+		C.ORT_BindingRun(b.ctx, &cErr)
+		return takeError(&cErr)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, bound := range b.outputs {
+		copy(bound.data, bound.cData)
+	}
+	return nil
+}
+
+// Close releases the binding, the bound tensors can be reused freely afterwards. It waits for a running Run.
+// Closing the last binding of a closed or reloaded predictor deletes its model, the error is then the one
+// of Predictor.Close unless deleting the binding failed.
+func (b *Binding) Close() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	if b.ctx == nil {
+		b.mu.Unlock()
+		return nil
+	}
+	var cErr C.ORT_Error
+	C.ORT_DeleteBinding(b.ctx, &cErr)
+	err := takeError(&cErr)
+	b.ctx = nil
+	b.inputs = nil
+	b.outputs = nil
+	b.mu.Unlock()
+
+	if releaseErr := b.session.release(); err == nil {
+		err = releaseErr
+	}
+
+	b.predictor.mu.Lock()
+	delete(b.predictor.bindings, b)
+	b.predictor.mu.Unlock()
+	return err
+}
@@ -0,0 +1,171 @@
+package onnxruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func doubleModel(t testing.TB) string {
+	return writeTestModel(t,
+		[]testNode{{opType: "Add", inputs: []string{"x", "x"}, outputs: []string{"y"}}},
+		[]testValue{{"x", tensorType(onnxFloat, 4)}},
+		[]testValue{{"y", tensorType(onnxFloat, 4)}},
+	)
+}
+
+func newFloat32Tensor(data []float32) *gotensor.Dense {
+	return gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(data), gotensor.WithShape(len(data)))
+}
+
+func TestBindingRun(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	binding, err := predictor.NewBinding()
+	if err != nil {
+		t.Fatalf("failed to create the binding %v", err)
+	}
+
+	input := []float32{1, 2, 3, 4}
+	output := make([]float32, 4)
+	assert.NoError(t, binding.BindInput("x", newFloat32Tensor(input)))
+	assert.NoError(t, binding.BindOutput("y", newFloat32Tensor(output)))
+
+	ctx := context.Background()
+	assert.NoError(t, binding.Run(ctx))
+	assert.Equal(t, []float32{2, 4, 6, 8}, output)
+
+	// the bound memory is read again on every run
+	copy(input, []float32{-1, 0, 0.5, 10})
+	assert.NoError(t, binding.Run(ctx))
+	assert.Equal(t, []float32{-2, 0, 1, 20}, output)
+
+	// binding again replaces the tensor, the previous one is left as it is
+	other := make([]float32, 4)
+	assert.NoError(t, binding.BindOutput("y", newFloat32Tensor(other)))
+	assert.NoError(t, binding.Run(ctx))
+	assert.Equal(t, []float32{-2, 0, 1, 20}, other)
+
+	// the tensors belong to the caller, they stay usable once the binding is closed
+	assert.NoError(t, binding.Close())
+	copy(output, []float32{5, 6, 7, 8})
+	assert.Equal(t, []float32{5, 6, 7, 8}, output)
+}
+
+func TestBindingInvalid(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	binding, err := predictor.NewBinding()
+	if err != nil {
+		t.Fatalf("failed to create the binding %v", err)
+	}
+	defer binding.Close()
+
+	assert.Error(t, binding.BindInput("x", gotensor.New(gotensor.Of(gotensor.String), gotensor.WithBacking([]string{"a"}), gotensor.WithShape(1))))
+	transposed := gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(make([]float32, 4)), gotensor.WithShape(2, 2))
+	assert.NoError(t, transposed.T())
+	assert.Error(t, binding.BindInput("x", transposed))
+}
+
+func TestBindingOutputShapeMismatch(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	binding, err := predictor.NewBinding()
+	if err != nil {
+		t.Fatalf("failed to create the binding %v", err)
+	}
+	defer binding.Close()
+
+	assert.NoError(t, binding.BindInput("x", newFloat32Tensor(make([]float32, 4))))
+	assert.NoError(t, binding.BindOutput("y", newFloat32Tensor(make([]float32, 2))))
+	assert.Error(t, binding.Run(context.Background()))
+}
+
+func TestBindingClosedWithPredictor(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+
+	binding, err := predictor.NewBinding()
+	if err != nil {
+		t.Fatalf("failed to create the binding %v", err)
+	}
+
+	predictor.Close()
+	assert.Error(t, binding.Run(context.Background()))
+	binding.Close()
+}
+
+// TestBindingCloseDuringRun closes the predictor while its binding is running, Close has to wait for the run
+func TestBindingCloseDuringRun(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+
+	binding, err := predictor.NewBinding()
+	if err != nil {
+		t.Fatalf("failed to create the binding %v", err)
+	}
+	assert.NoError(t, binding.BindInput("x", newFloat32Tensor(make([]float32, 4))))
+	assert.NoError(t, binding.BindOutput("y", newFloat32Tensor(make([]float32, 4))))
+
+	ctx := context.Background()
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		close(started)
+		for binding.Run(ctx) == nil {
+		}
+	}()
+
+	<-started
+	assert.NoError(t, predictor.Close())
+	<-done
+	assert.Error(t, binding.Run(ctx))
+	assert.NoError(t, binding.Close())
+}
+
+func benchmarkRun(b *testing.B, binding bool) {
+	predictor := newCPUPredictor(b, doubleModel(b))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	if binding {
+		bind, err := predictor.NewBinding()
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer bind.Close()
+		if err := bind.BindInput("x", newFloat32Tensor(make([]float32, 4))); err != nil {
+			b.Fatal(err)
+		}
+		if err := bind.BindOutput("y", newFloat32Tensor(make([]float32, 4))); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := bind.Run(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+		return
+	}
+
+	input := gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(make([]float32, 4)), gotensor.WithShape(4))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runTestPredictor(b, predictor, input)
+	}
+}
+
+// BenchmarkPredict copies the inputs into onnxruntime and the outputs out of it on every prediction
+func BenchmarkPredict(b *testing.B) {
+	benchmarkRun(b, false)
+}
+
+// BenchmarkBindingRun copies the bound tensors into the memory bound once, without any allocation
+func BenchmarkBindingRun(b *testing.B) {
+	benchmarkRun(b, true)
+}
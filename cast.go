@@ -0,0 +1,361 @@
+package onnxruntime
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/c3sr/dlframework/framework/options"
+	"github.com/pkg/errors"
+)
+
+// OverflowPolicy decides what happens to the values out of the range of the type an input is cast to
+type OverflowPolicy int
+
+const (
+	// OverflowError fails the prediction
+	OverflowError OverflowPolicy = iota
+	// OverflowSaturate clamps the values to the largest or the smallest finite value of the type,
+	// NaN becomes 0 when cast to an integer type
+	OverflowSaturate
+)
+
+// PrecisionPolicy decides what happens to the values the type an input is cast to can not represent exactly
+type PrecisionPolicy int
+
+const (
+	// PrecisionRound rounds to the nearest value of floating point types and truncates towards zero for integer types
+	PrecisionRound PrecisionPolicy = iota
+	// PrecisionError fails the prediction
+	PrecisionError
+)
+
+// CastPolicy configures the casts done by the predictor when AutoCast is enabled,
+// the zero value saturates nothing and rounds
+type CastPolicy struct {
+	Overflow  OverflowPolicy
+	Precision PrecisionPolicy
+}
+
+type autoCastKey struct{}
+
+// AutoCast makes the predictor cast the tensor inputs to the element types declared by the model,
+// e.g. float64 or uint8 data for a float32 input, instead of leaving the mismatch to onnxruntime.
+// The converted inputs are listed in the converted_inputs tag of the c_predict span.
+func AutoCast(policy CastPolicy) options.Option {
+	return func(o *options.Options) {
+		ctx := o.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		o.SetContext(context.WithValue(ctx, autoCastKey{}, policy))
+	}
+}
+
+func autoCastPolicy(o *options.Options) (CastPolicy, bool) {
+	ctx := o.Context()
+	if ctx == nil {
+		return CastPolicy{}, false
+	}
+	policy, ok := ctx.Value(autoCastKey{}).(CastPolicy)
+	return policy, ok
+}
+
+// number is an element of a numeric slice, kept exactly whatever its type:
+// kind is reflect.Int64 for signed integers, reflect.Uint64 for unsigned integers and bools,
+// and reflect.Float64 for floating point numbers
+type number struct {
+	kind reflect.Kind
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func (x number) float() float64 {
+	switch x.kind {
+	case reflect.Int64:
+		return float64(x.i)
+	case reflect.Uint64:
+		return float64(x.u)
+	default:
+		return x.f
+	}
+}
+
+func (x number) isZero() bool {
+	return x.i == 0 && x.u == 0 && x.f == 0
+}
+
+// exact reports whether f is the same value as x
+func (x number) exact(f float64) bool {
+	switch x.kind {
+	case reflect.Int64:
+		return math.Trunc(f) == f && f >= -(1<<63) && f < 1<<63 && int64(f) == x.i
+	case reflect.Uint64:
+		return math.Trunc(f) == f && f >= 0 && f < 1<<64 && uint64(f) == x.u
+	default:
+		return f == x.f || (math.IsNaN(f) && math.IsNaN(x.f))
+	}
+}
+
+func (x number) String() string {
+	switch x.kind {
+	case reflect.Int64:
+		return strconv.FormatInt(x.i, 10)
+	case reflect.Uint64:
+		return strconv.FormatUint(x.u, 10)
+	default:
+		return strconv.FormatFloat(x.f, 'g', -1, 64)
+	}
+}
+
+/* Description: Get an accessor to the elements of a numeric or bool slice, false for any other data */
+func numberReader(data interface{}) (func(int) number, bool) {
+	switch d := data.(type) {
+	case []float32:
+		return func(i int) number { return number{kind: reflect.Float64, f: float64(d[i])} }, true
+	case []float64:
+		return func(i int) number { return number{kind: reflect.Float64, f: d[i]} }, true
+	case []Float16:
+		return func(i int) number { return number{kind: reflect.Float64, f: float64(d[i].Float32())} }, true
+	case []BFloat16:
+		return func(i int) number { return number{kind: reflect.Float64, f: float64(d[i].Float32())} }, true
+	case []int8:
+		return func(i int) number { return number{kind: reflect.Int64, i: int64(d[i])} }, true
+	case []int16:
+		return func(i int) number { return number{kind: reflect.Int64, i: int64(d[i])} }, true
+	case []int32:
+		return func(i int) number { return number{kind: reflect.Int64, i: int64(d[i])} }, true
+	case []int64:
+		return func(i int) number { return number{kind: reflect.Int64, i: d[i]} }, true
+	case []uint8:
+		return func(i int) number { return number{kind: reflect.Uint64, u: uint64(d[i])} }, true
+	case []uint16:
+		return func(i int) number { return number{kind: reflect.Uint64, u: uint64(d[i])} }, true
+	case []uint32:
+		return func(i int) number { return number{kind: reflect.Uint64, u: uint64(d[i])} }, true
+	case []uint64:
+		return func(i int) number { return number{kind: reflect.Uint64, u: d[i]} }, true
+	case []bool:
+		return func(i int) number {
+			if d[i] {
+				return number{kind: reflect.Uint64, u: 1}
+			}
+			return number{kind: reflect.Uint64}
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+func (p CastPolicy) overflow(x number, to reflect.Type) error {
+	if p.Overflow == OverflowSaturate {
+		return nil
+	}
+	return errors.Errorf("value %v overflows %v", x, to)
+}
+
+func (p CastPolicy) imprecise(x number, to reflect.Type) error {
+	if p.Precision == PrecisionRound {
+		return nil
+	}
+	return errors.Errorf("value %v can not be represented exactly as %v", x, to)
+}
+
+// toFloat casts x to a floating point type whose largest finite value is max and whose rounding is round
+func (p CastPolicy) toFloat(x number, to reflect.Type, max float64, round func(float64) float64) (float64, error) {
+	v := x.float()
+	r := round(v)
+	if math.IsInf(r, 0) && !math.IsInf(v, 0) {
+		return math.Copysign(max, v), p.overflow(x, to)
+	}
+	if !x.exact(r) {
+		return r, p.imprecise(x, to)
+	}
+	return r, nil
+}
+
+// toInt casts x to a signed integer type of the given number of bits
+func (p CastPolicy) toInt(x number, to reflect.Type, bits uint) (int64, error) {
+	min, max := -int64(1)<<(bits-1), int64(1)<<(bits-1)-1
+	switch x.kind {
+	case reflect.Int64:
+		if x.i < min {
+			return min, p.overflow(x, to)
+		}
+		if x.i > max {
+			return max, p.overflow(x, to)
+		}
+		return x.i, nil
+	case reflect.Uint64:
+		if x.u > uint64(max) {
+			return max, p.overflow(x, to)
+		}
+		return int64(x.u), nil
+	default:
+		if math.IsNaN(x.f) {
+			return 0, p.overflow(x, to)
+		}
+		t := math.Trunc(x.f)
+		// -min is the power of two just above max, which float64 holds exactly
+		if t < float64(min) {
+			return min, p.overflow(x, to)
+		}
+		if t >= -float64(min) {
+			return max, p.overflow(x, to)
+		}
+		if t != x.f {
+			return int64(t), p.imprecise(x, to)
+		}
+		return int64(t), nil
+	}
+}
+
+// toUint casts x to an unsigned integer type of the given number of bits
+func (p CastPolicy) toUint(x number, to reflect.Type, bits uint) (uint64, error) {
+	max := uint64(math.MaxUint64) >> (64 - bits)
+	switch x.kind {
+	case reflect.Int64:
+		if x.i < 0 {
+			return 0, p.overflow(x, to)
+		}
+		if uint64(x.i) > max {
+			return max, p.overflow(x, to)
+		}
+		return uint64(x.i), nil
+	case reflect.Uint64:
+		if x.u > max {
+			return max, p.overflow(x, to)
+		}
+		return x.u, nil
+	default:
+		if math.IsNaN(x.f) {
+			return 0, p.overflow(x, to)
+		}
+		t := math.Trunc(x.f)
+		if t < 0 {
+			return 0, p.overflow(x, to)
+		}
+		if t >= math.Ldexp(1, int(bits)) {
+			return max, p.overflow(x, to)
+		}
+		if t != x.f {
+			return uint64(t), p.imprecise(x, to)
+		}
+		return uint64(t), nil
+	}
+}
+
+// toBool casts x to a bool, every value but zero is true
+func (p CastPolicy) toBool(x number, to reflect.Type) (bool, error) {
+	if x.isZero() {
+		return false, nil
+	}
+	if !x.exact(1) {
+		return true, p.imprecise(x, to)
+	}
+	return true, nil
+}
+
+func roundFloat32(f float64) float64 { return float64(float32(f)) }
+
+func roundFloat64(f float64) float64 { return f }
+
+func roundFloat16(f float64) float64 { return float64(NewFloat16(float32(f)).Float32()) }
+
+func roundBFloat16(f float64) float64 { return float64(NewBFloat16(float32(f)).Float32()) }
+
+/* Description: Cast the elements of a Value to the element type to, following the policy for narrowing casts
+ *              Only numeric and bool values can be cast, the result is a new slice
+ */
+func castValue(v Value, to reflect.Type, policy CastPolicy) (Value, error) {
+	read, ok := numberReader(v.Data)
+	if !ok {
+		return Value{}, errors.Errorf("can not cast %T data to %v", v.Data, to)
+	}
+
+	n := reflect.ValueOf(v.Data).Len()
+	res := reflect.MakeSlice(reflect.SliceOf(to), n, n)
+
+	var err error
+	switch out := res.Interface().(type) {
+	case []float32:
+		for i := 0; i < n && err == nil; i++ {
+			var f float64
+			f, err = policy.toFloat(read(i), to, math.MaxFloat32, roundFloat32)
+			out[i] = float32(f)
+		}
+	case []float64:
+		for i := 0; i < n && err == nil; i++ {
+			out[i], err = policy.toFloat(read(i), to, math.MaxFloat64, roundFloat64)
+		}
+	case []Float16:
+		for i := 0; i < n && err == nil; i++ {
+			var f float64
+			f, err = policy.toFloat(read(i), to, 65504, roundFloat16)
+			out[i] = NewFloat16(float32(f))
+		}
+	case []BFloat16:
+		for i := 0; i < n && err == nil; i++ {
+			var f float64
+			f, err = policy.toFloat(read(i), to, float64(BFloat16(0x7f7f).Float32()), roundBFloat16)
+			out[i] = NewBFloat16(float32(f))
+		}
+	case []int8:
+		for i := 0; i < n && err == nil; i++ {
+			var x int64
+			x, err = policy.toInt(read(i), to, 8)
+			out[i] = int8(x)
+		}
+	case []int16:
+		for i := 0; i < n && err == nil; i++ {
+			var x int64
+			x, err = policy.toInt(read(i), to, 16)
+			out[i] = int16(x)
+		}
+	case []int32:
+		for i := 0; i < n && err == nil; i++ {
+			var x int64
+			x, err = policy.toInt(read(i), to, 32)
+			out[i] = int32(x)
+		}
+	case []int64:
+		for i := 0; i < n && err == nil; i++ {
+			out[i], err = policy.toInt(read(i), to, 64)
+		}
+	case []uint8:
+		for i := 0; i < n && err == nil; i++ {
+			var x uint64
+			x, err = policy.toUint(read(i), to, 8)
+			out[i] = uint8(x)
+		}
+	case []uint16:
+		for i := 0; i < n && err == nil; i++ {
+			var x uint64
+			x, err = policy.toUint(read(i), to, 16)
+			out[i] = uint16(x)
+		}
+	case []uint32:
+		for i := 0; i < n && err == nil; i++ {
+			var x uint64
+			x, err = policy.toUint(read(i), to, 32)
+			out[i] = uint32(x)
+		}
+	case []uint64:
+		for i := 0; i < n && err == nil; i++ {
+			out[i], err = policy.toUint(read(i), to, 64)
+		}
+	case []bool:
+		for i := 0; i < n && err == nil; i++ {
+			out[i], err = policy.toBool(read(i), to)
+		}
+	default:
+		return Value{}, errors.Errorf("can not cast %T data to %v", v.Data, to)
+	}
+	if err != nil {
+		return Value{}, err
+	}
+
+	return Value{Data: res.Interface(), Shape: v.Shape}, nil
+}
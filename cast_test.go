@@ -0,0 +1,205 @@
+package onnxruntime
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/c3sr/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func castTo(data interface{}, to interface{}, policy CastPolicy) (interface{}, error) {
+	v, err := castValue(Value{Data: data, Shape: []int64{int64(reflect.ValueOf(data).Len())}}, reflect.TypeOf(to), policy)
+	return v.Data, err
+}
+
+func TestCastWidening(t *testing.T) {
+	strict := CastPolicy{Overflow: OverflowError, Precision: PrecisionError}
+
+	res, err := castTo([]uint8{0, 128, 255}, float32(0), strict)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{0, 128, 255}, res)
+
+	res, err = castTo([]int32{-5, 7}, int64(0), strict)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{-5, 7}, res)
+
+	res, err = castTo([]bool{true, false}, float32(0), strict)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{1, 0}, res)
+
+	res, err = castTo([]float64{0.5, -2, math.Inf(1)}, float32(0), strict)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{0.5, -2, float32(math.Inf(1))}, res)
+
+	res, err = castTo([]float32{0.5, 1024}, Float16(0), strict)
+	assert.NoError(t, err)
+	assert.Equal(t, []Float16{NewFloat16(0.5), NewFloat16(1024)}, res)
+}
+
+func TestCastPrecision(t *testing.T) {
+	round := CastPolicy{}
+	strict := CastPolicy{Precision: PrecisionError}
+
+	res, err := castTo([]float64{0.1}, float32(0), round)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{0.1}, res)
+	_, err = castTo([]float64{0.1}, float32(0), strict)
+	assert.Error(t, err)
+
+	res, err = castTo([]float64{2.9, -2.9}, int8(0), round)
+	assert.NoError(t, err)
+	assert.Equal(t, []int8{2, -2}, res)
+	_, err = castTo([]float64{2.9}, int8(0), strict)
+	assert.Error(t, err)
+
+	_, err = castTo([]int64{1<<53 + 1}, float64(0), strict)
+	assert.Error(t, err)
+	_, err = castTo([]uint8{2}, false, strict)
+	assert.Error(t, err)
+}
+
+func TestCastOverflow(t *testing.T) {
+	fail := CastPolicy{Overflow: OverflowError}
+	saturate := CastPolicy{Overflow: OverflowSaturate}
+
+	_, err := castTo([]float64{1e39}, float32(0), fail)
+	assert.Error(t, err)
+	res, err := castTo([]float64{1e39, -1e39}, float32(0), saturate)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{math.MaxFloat32, -math.MaxFloat32}, res)
+
+	_, err = castTo([]int32{300}, uint8(0), fail)
+	assert.Error(t, err)
+	res, err = castTo([]int32{300, -1}, uint8(0), saturate)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint8{255, 0}, res)
+
+	res, err = castTo([]float64{1e20, -1e20, math.NaN()}, int64(0), saturate)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{math.MaxInt64, math.MinInt64, 0}, res)
+	_, err = castTo([]float64{math.NaN()}, int32(0), fail)
+	assert.Error(t, err)
+
+	res, err = castTo([]uint64{math.MaxUint64}, int64(0), saturate)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{math.MaxInt64}, res)
+
+	_, err = castTo([]float32{70000}, Float16(0), fail)
+	assert.Error(t, err)
+	res, err = castTo([]float32{70000}, Float16(0), saturate)
+	assert.NoError(t, err)
+	assert.Equal(t, []Float16{0x7bff}, res)
+
+	_, err = castTo([]string{"1"}, float32(0), saturate)
+	assert.Error(t, err)
+}
+
+func TestAutoCastInputs(t *testing.T) {
+	ctx := context.Background()
+	model := identityModel(t, onnxFloat)
+
+	predictor := newCPUPredictor(t, model, AutoCast(CastPolicy{}))
+	defer predictor.Close()
+
+	outputs := runTestPredictor(t, predictor, gotensor.New(gotensor.WithBacking([]float64{0.5, 1e-3}), gotensor.WithShape(2)))
+	assert.Equal(t, []float32{0.5, 1e-3}, outputs[0].Data())
+
+	outputs = runTestPredictor(t, predictor, gotensor.New(gotensor.WithBacking([]uint8{0, 255}), gotensor.WithShape(2)))
+	assert.Equal(t, []float32{0, 255}, outputs[0].Data())
+
+	_, err := predictor.PredictSlices(ctx, []Value{{Data: []float64{1e39}, Shape: []int64{1}}})
+	assert.Error(t, err)
+
+	uncast := newCPUPredictor(t, model)
+	defer uncast.Close()
+
+	_, err = uncast.PredictSlices(ctx, []Value{{Data: []float64{0.5}, Shape: []int64{1}}})
+	assert.Error(t, err)
+}
+
+// recordingTracer is a tracer keeping the finished spans, so that the tests can check their tags
+type recordingTracer struct {
+	*mocktracer.MockTracer
+	level tracer.Level
+}
+
+// useRecordingTracer replaces the standard tracer until the returned function is called
+func useRecordingTracer(level tracer.Level) (*recordingTracer, func()) {
+	previous := tracer.Std()
+	rec := &recordingTracer{MockTracer: mocktracer.New(), level: level}
+	tracer.SetStd(rec)
+	return rec, func() {
+		tracer.SetStd(previous)
+	}
+}
+
+func (r *recordingTracer) ID() string { return "recording" }
+
+func (r *recordingTracer) StartSpanFromContext(ctx context.Context, operationName string, opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	return opentracing.StartSpanFromContextWithTracer(ctx, r, operationName, opts...)
+}
+
+func (r *recordingTracer) Close() error { return nil }
+
+func (r *recordingTracer) Init(serviceName string, opts ...tracer.Option) error { return nil }
+
+func (r *recordingTracer) Name() string { return "recording" }
+
+func (r *recordingTracer) Level() tracer.Level { return r.level }
+
+func (r *recordingTracer) SetLevel(level tracer.Level) { r.level = level }
+
+func (r *recordingTracer) Endpoints() []string { return nil }
+
+// predictSpans returns the tags of the finished c_predict spans
+func (r *recordingTracer) predictSpans() []map[string]interface{} {
+	res := []map[string]interface{}{}
+	for _, span := range r.FinishedSpans() {
+		if span.OperationName == "c_predict" {
+			res = append(res, span.Tags())
+		}
+	}
+	return res
+}
+
+func TestAutoCastSpanTag(t *testing.T) {
+	rec, restore := useRecordingTracer(tracer.MODEL_TRACE)
+	defer restore()
+
+	model := writeTestModel(t,
+		[]testNode{{opType: "Add", inputs: []string{"a", "b"}, outputs: []string{"y"}}},
+		[]testValue{{"a", tensorType(onnxFloat, 2)}, {"b", tensorType(onnxFloat, 2)}},
+		[]testValue{{"y", tensorType(onnxFloat, 2)}},
+	)
+	predictor := newCPUPredictor(t, model, AutoCast(CastPolicy{}))
+	defer predictor.Close()
+
+	// only a is cast, b already has the type of the model
+	outputs := runTestPredictor(t, predictor,
+		gotensor.New(gotensor.WithBacking([]float64{0.5, 1}), gotensor.WithShape(2)),
+		gotensor.New(gotensor.WithBacking([]float32{1, 2}), gotensor.WithShape(2)))
+	assert.Equal(t, []float32{1.5, 3}, outputs[0].Data())
+
+	// no tag when nothing is cast
+	runTestPredictor(t, predictor,
+		gotensor.New(gotensor.WithBacking([]float32{0.5, 1}), gotensor.WithShape(2)),
+		gotensor.New(gotensor.WithBacking([]float32{1, 2}), gotensor.WithShape(2)))
+
+	// both inputs are cast, in the order of the inputs
+	runTestPredictor(t, predictor,
+		gotensor.New(gotensor.WithBacking([]uint8{1, 2}), gotensor.WithShape(2)),
+		gotensor.New(gotensor.WithBacking([]float64{1, 2}), gotensor.WithShape(2)))
+
+	spans := rec.predictSpans()
+	if assert.Len(t, spans, 3) {
+		assert.Equal(t, "a:float64->float32", spans[0]["converted_inputs"])
+		assert.NotContains(t, spans[1], "converted_inputs")
+		assert.Equal(t, "a:uint8->float32,b:float64->float32", spans[2]["converted_inputs"])
+	}
+}
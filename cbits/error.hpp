@@ -15,20 +15,24 @@
     if (errVar.message != nullptr) {   \
       free(errVar.message);            \
     }                                  \
-    errVar.message = nullptr
+    errVar.message = nullptr;          \
+    errVar.code = ORT_OK
 
 
+/* Description: The exceptions are reported to Go with the OrtErrorCode of onnxruntime, when they carry one */
 #define END_HANDLE_ORT_ERRORS(errVar, retVal)                    \
   }                                                              \
+  catch (const Ort::Exception &e) {                              \
+    errVar.code = e.GetOrtErrorCode();                           \
+    errVar.message = strdup(e.what());                           \
+  }                                                              \
   catch (const onnxruntime::OnnxRuntimeException &e) {           \
-    auto msg = e.what();                                         \
-    std::cout << "Onnxruntime Exception msg = " << msg << "\n";  \
-    errVar.message = strdup(msg);                                \
+    errVar.code = ORT_RUNTIME_EXCEPTION;                         \
+    errVar.message = strdup(e.what());                           \
   }                                                              \
   catch (const std::exception &e) {                              \
-    auto msg = e.what();                                         \
-    std::cout << "Std Exception msg = " << msg << "\n";          \
-    errVar.message = strdup(msg);                                \
+    errVar.code = ORT_FAIL;                                      \
+    errVar.message = strdup(e.what());                           \
   }                                                              \
   return retVal
 
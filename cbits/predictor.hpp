@@ -10,8 +10,11 @@ extern "C" {
 #include <onnxruntime_c_api.h>
 #endif  /* __cplusplus */
 
+  // Every entry point reports its errors through its own ORT_Error out-parameter, which has to be
+  // initialized: a message left from a previous call is freed, a new one is allocated with malloc
   typedef struct ORT_Error {
     char* message;
+    OrtErrorCode code; // ORT_OK when there is no error
   } ORT_Error;
 
   typedef struct ORT_Value {
@@ -19,45 +22,109 @@ extern "C" {
     void *data_ptr;
     int64_t *shape_ptr;
     size_t shape_len;
+    size_t *offsets_ptr; // string tensors only: shape size + 1 offsets into data_ptr
+    enum ONNXType vtype;
+    struct ORT_Value *elements_ptr; // sequences: the elements, maps: the keys followed by the values
+    size_t elements_len;
   } ORT_Value;
 
-  extern ORT_Error ORT_GlobalError;
+  typedef struct ORT_NodeInfo {
+    const char *name;
+    enum ONNXType vtype;
+    ONNXTensorElementDataType otype; // tensors only
+    int64_t *shape_ptr; // tensors only, -1 for the dimensions of unknown size
+    size_t shape_len;
+    ONNXTensorElementDataType key_otype; // maps only
+    ONNXTensorElementDataType value_otype; // the values of maps and the elements of sequences of tensors only
+  } ORT_NodeInfo;
+
+  typedef struct ORT_MemoryStats {
+    int64_t tensor_bytes;
+    int64_t peak_tensor_bytes;
+    int64_t converted_bytes;
+    int64_t peak_converted_bytes;
+  } ORT_MemoryStats;
+
   typedef enum { UNKNOWN_DEVICE_KIND = -1, CPU_DEVICE_KIND = 0, CUDA_DEVICE_KIND = 1 } ORT_DeviceKind;
   typedef void* ORT_PredictorContext;
   typedef void* ORT_TensorContext;
+  typedef void* ORT_ValueContext;
+  typedef void* ORT_BindingContext;
+  typedef void* ORT_RunContext;
 
   // Predictor + Profiling interface for Go
 
-  ORT_PredictorContext ORT_NewPredictor(const char *model_file, ORT_DeviceKind device, bool enable_trace, int device_id);
+  ORT_PredictorContext ORT_NewPredictor(const char *model_file, ORT_DeviceKind device, bool enable_trace, int device_id,
+                                        ORT_Error *err);
+
+  int ORT_PredictorNumInputs(ORT_PredictorContext pred, ORT_Error *err);
+
+  int ORT_PredictorNumOutputs(ORT_PredictorContext pred, ORT_Error *err);
+
+  ORT_NodeInfo ORT_PredictorNodeInfo(ORT_PredictorContext pred, bool input, int index, ORT_Error *err);
 
-  void ORT_PredictorClear(ORT_PredictorContext pred);
+  ORT_Value ORT_ValueConvert(ORT_ValueContext value, bool view, ORT_Error *err);
 
-  void ORT_PredictorRun(ORT_PredictorContext pred);
+  void ORT_ValueFree(ORT_Value value, bool view, ORT_Error *err);
 
-  void ORT_PredictorConvertOutput(ORT_PredictorContext pred);
+  ORT_MemoryStats ORT_PredictorMemoryStats(ORT_PredictorContext pred, ORT_Error *err);
 
-  int ORT_PredictorNumOutputs(ORT_PredictorContext pred);
+  void ORT_PredictorDelete(ORT_PredictorContext pred, ORT_Error *err);
 
-  ORT_Value ORT_PredictorGetOutput(ORT_PredictorContext pred, int index);
+  char *ORT_ProfilingRead(ORT_PredictorContext pred, ORT_Error *err);
 
-  void ORT_PredictorDelete(ORT_PredictorContext pred);
+  int64_t ORT_ProfilingGetStartTime(ORT_PredictorContext pred, ORT_Error *err);
 
-  char *ORT_ProfilingRead(ORT_PredictorContext pred);
+  int64_t ORT_ThreadID(ORT_Error *err);
 
-  int64_t ORT_ProfilingGetStartTime(ORT_PredictorContext pred);
+  ORT_ValueContext ORT_NewTensorValue(const void *input, int64_t *dimensions, int n_dim, ONNXTensorElementDataType dtype,
+                                      ORT_Error *err);
 
-  void ORT_AddInput(ORT_PredictorContext pred, void *input, int64_t *dimensions,
-                    int n_dim, ONNXTensorElementDataType dtype);
+  ORT_ValueContext ORT_NewStringTensorValue(const char **input, int64_t *dimensions, int n_dim, ORT_Error *err);
+
+  ORT_ValueContext ORT_NewSequenceValue(ORT_ValueContext *elements, int n_elements, ORT_Error *err);
+
+  ORT_ValueContext ORT_NewMapValue(ORT_ValueContext keys, ORT_ValueContext values, ORT_Error *err);
+
+  void ORT_DeleteValue(ORT_ValueContext value, ORT_Error *err);
   
-  void ORT_EndProfiling(ORT_PredictorContext pred);
+  void ORT_EndProfiling(ORT_PredictorContext pred, ORT_Error *err);
+
+  // Run interface for Go, one run holds the inputs and the outputs of one prediction
+
+  ORT_RunContext ORT_NewRun(ORT_PredictorContext pred, ORT_Error *err);
+
+  void ORT_RunAddInput(ORT_RunContext run, ORT_ValueContext value, ORT_Error *err);
+
+  void ORT_RunPredict(ORT_RunContext run, ORT_Error *err);
+
+  void ORT_RunTerminate(ORT_RunContext run, ORT_Error *err);
+
+  void ORT_RunConvertOutput(ORT_RunContext run, ORT_Error *err);
+
+  int ORT_RunNumOutputs(ORT_RunContext run, ORT_Error *err);
+
+  ORT_Value ORT_RunGetOutput(ORT_RunContext run, int index, ORT_Error *err);
+
+  int ORT_RunNumOutputValues(ORT_RunContext run, ORT_Error *err);
+
+  ORT_ValueContext ORT_RunTakeOutput(ORT_RunContext run, int index, ORT_Error *err);
+
+  void ORT_DeleteRun(ORT_RunContext run, ORT_Error *err);
+
+  // IO binding interface for Go
+
+  ORT_BindingContext ORT_NewBinding(ORT_PredictorContext pred, ORT_Error *err);
 
-  // Error interface for Go
+  void *ORT_BindingBindInput(ORT_BindingContext bind, const char *name, int64_t *dimensions,
+                             int n_dim, ONNXTensorElementDataType dtype, ORT_Error *err);
 
-  int ORT_HasError();
+  void *ORT_BindingBindOutput(ORT_BindingContext bind, const char *name, int64_t *dimensions,
+                              int n_dim, ONNXTensorElementDataType dtype, ORT_Error *err);
 
-  const char* ORT_GetErrorString();
+  void ORT_BindingRun(ORT_BindingContext bind, ORT_Error *err);
 
-  void ORT_ResetError();
+  void ORT_DeleteBinding(ORT_BindingContext bind, ORT_Error *err);
 
 #ifdef __cplusplus
 }
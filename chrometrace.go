@@ -0,0 +1,131 @@
+package onnxruntime
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Span is an operation of the Go side of a predictor, such as c_new, c_predict or c_read_predicted_output
+type Span struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// the processes of the Chrome trace, the spans are shown above the onnxruntime events
+const (
+	chromeSpansPID = iota + 1
+	chromeOnnxruntimePID
+)
+
+// chromeEvent is an event of the Chrome Trace Event format, the times are in microseconds
+type chromeEvent struct {
+	Category  string                 `json:"cat,omitempty"`
+	Name      string                 `json:"name"`
+	Phase     string                 `json:"ph"`
+	Timestamp float64                `json:"ts"`
+	Duration  float64                `json:"dur,omitempty"`
+	ProcessID int64                  `json:"pid"`
+	ThreadID  int64                  `json:"tid"`
+	Arguments map[string]interface{} `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes the events of an onnxruntime profile and the spans as a Chrome Trace Event file,
+// which chrome://tracing and Perfetto open. Both are timed with the system clock, the file starts at the earliest of them.
+// The onnxruntime events keep their threads, the spans are laid out on as many tracks as needed for them not to overlap.
+func WriteChromeTrace(w io.Writer, t *Trace, spans []Span) error {
+	origin := t.StartTime
+	for _, span := range spans {
+		if origin.IsZero() || span.Start.Before(origin) {
+			origin = span.Start
+		}
+	}
+	micros := func(d time.Duration) float64 {
+		return float64(d.Nanoseconds()) / 1000
+	}
+
+	events := []chromeEvent{
+		chromeMetadata("process_name", chromeSpansPID, 0, "go-onnxruntime"),
+		chromeMetadata("process_name", chromeOnnxruntimePID, 0, "onnxruntime"),
+	}
+
+	spans = append([]Span(nil), spans...)
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].Start.Before(spans[j].Start)
+	})
+	// laneEnds holds when the last span of each track ends
+	var laneEnds []time.Time
+	for _, span := range spans {
+		lane := 0
+		for lane < len(laneEnds) && laneEnds[lane].After(span.Start) {
+			lane++
+		}
+		if lane == len(laneEnds) {
+			laneEnds = append(laneEnds, time.Time{})
+			events = append(events, chromeMetadata("thread_name", chromeSpansPID, int64(lane+1), "spans "+strconv.Itoa(lane+1)))
+		}
+		laneEnds[lane] = span.End
+
+		events = append(events, chromeEvent{
+			Category:  "go",
+			Name:      span.Name,
+			Phase:     "X",
+			Timestamp: micros(span.Start.Sub(origin)),
+			Duration:  micros(span.End.Sub(span.Start)),
+			ProcessID: chromeSpansPID,
+			ThreadID:  int64(lane + 1),
+		})
+	}
+
+	for _, event := range t.TraceEvents {
+		phase := event.Phase
+		if phase == "" {
+			phase = "X"
+		}
+		var args map[string]interface{}
+		if len(event.Arguments) > 0 {
+			args = make(map[string]interface{}, len(event.Arguments))
+			for key, value := range event.Arguments {
+				args[key] = value
+			}
+		}
+		events = append(events, chromeEvent{
+			Category:  event.Category,
+			Name:      event.Name,
+			Phase:     phase,
+			Timestamp: micros(event.StartTime.Sub(origin)),
+			Duration:  micros(event.EndTime.Sub(event.StartTime)),
+			ProcessID: chromeOnnxruntimePID,
+			ThreadID:  event.ThreadID,
+			Arguments: args,
+		})
+	}
+
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []chromeEvent `json:"traceEvents"`
+		DisplayTimeUnit string        `json:"displayTimeUnit"`
+	}{events, "ms"})
+}
+
+func chromeMetadata(name string, pid, tid int64, value string) chromeEvent {
+	return chromeEvent{
+		Name:      name,
+		Phase:     "M",
+		ProcessID: pid,
+		ThreadID:  tid,
+		Arguments: map[string]interface{}{"name": value},
+	}
+}
+
+// WriteChromeTrace ends the profiling of the current model of the predictor like Profile, and writes its profile
+// along with the c_new, c_predict and c_read_predicted_output spans recorded until then as a Chrome trace
+func (p *Predictor) WriteChromeTrace(w io.Writer) error {
+	t, spans, err := p.endProfile()
+	if err != nil {
+		return err
+	}
+	return WriteChromeTrace(w, t, spans)
+}
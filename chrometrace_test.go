@@ -0,0 +1,90 @@
+package onnxruntime
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+// chromeTraceFile is the part of a Chrome trace file checked by the tests
+type chromeTraceFile struct {
+	TraceEvents []chromeEvent `json:"traceEvents"`
+}
+
+func readChromeTrace(t *testing.T, data []byte) chromeTraceFile {
+	var file chromeTraceFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("failed to parse the chrome trace %v", err)
+	}
+	return file
+}
+
+func TestWriteChromeTrace(t *testing.T) {
+	// the profile starts 1ms after the first span
+	start := time.Unix(100, 0)
+	trace, err := NewTrace(`[
+		{"cat": "Session", "name": "model_run", "ph": "X", "ts": 10, "dur": 20, "pid": 42, "tid": 7},
+		{"cat": "Node", "name": "add_kernel_time", "ph": "X", "ts": 12, "dur": 5, "pid": 42, "tid": 7, "args": {"op_name": "Add"}}
+	]`, start.Add(time.Millisecond).UnixNano())
+	if err != nil {
+		t.Fatalf("failed to parse the trace %v", err)
+	}
+	spans := []Span{
+		{Name: "c_new", Start: start, End: start.Add(500 * time.Microsecond)},
+		{Name: "c_predict", Start: start.Add(1005 * time.Microsecond), End: start.Add(1035 * time.Microsecond)},
+		// overlaps the previous span, it goes on another track
+		{Name: "c_predict", Start: start.Add(1010 * time.Microsecond), End: start.Add(1040 * time.Microsecond)},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteChromeTrace(&buf, trace, spans))
+	file := readChromeTrace(t, buf.Bytes())
+
+	var events []chromeEvent
+	for _, event := range file.TraceEvents {
+		if event.Phase != "M" {
+			events = append(events, event)
+		}
+	}
+	if !assert.Len(t, events, 5) {
+		return
+	}
+	assert.Equal(t, chromeEvent{Category: "go", Name: "c_new", Phase: "X", Timestamp: 0, Duration: 500, ProcessID: chromeSpansPID, ThreadID: 1}, events[0])
+	assert.Equal(t, float64(1005), events[1].Timestamp)
+	assert.Equal(t, int64(1), events[1].ThreadID)
+	assert.Equal(t, int64(2), events[2].ThreadID)
+
+	assert.Equal(t, "model_run", events[3].Name)
+	assert.Equal(t, float64(1010), events[3].Timestamp)
+	assert.Equal(t, float64(20), events[3].Duration)
+	assert.Equal(t, int64(chromeOnnxruntimePID), events[3].ProcessID)
+	assert.Equal(t, int64(7), events[3].ThreadID)
+	assert.Equal(t, map[string]interface{}{"op_name": "Add"}, events[4].Arguments)
+}
+
+func TestPredictorWriteChromeTrace(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t), EnableProfiling())
+	defer predictor.Close()
+
+	runTestPredictor(t, predictor,
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)))
+
+	var buf bytes.Buffer
+	if err := predictor.WriteChromeTrace(&buf); err != nil {
+		t.Fatalf("Onnxruntime predictor write chrome trace failed %v", err)
+	}
+	file := readChromeTrace(t, buf.Bytes())
+
+	names := map[string]bool{}
+	for _, event := range file.TraceEvents {
+		assert.True(t, event.Timestamp >= 0)
+		names[event.Name] = true
+	}
+	for _, name := range []string{"c_new", "c_predict", "c_read_predicted_output", "model_run"} {
+		assert.True(t, names[name], "missing event %s", name)
+	}
+}
@@ -0,0 +1,111 @@
+package onnxruntime
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+// TestConcurrentPredict runs many predictions on the same predictor at once, each goroutine checks
+// that it reads back the outputs of its own inputs. Run it with -race.
+func TestConcurrentPredict(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	const goroutines = 16
+	const iterations = 50
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				x := float32(g*iterations + i)
+				input := []float32{x, x + 1, x + 2, x + 3}
+
+				result, err := predictor.Predict(ctx, []gotensor.Tensor{
+					gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(input), gotensor.WithShape(4)),
+				})
+				if err != nil {
+					t.Errorf("Onnxruntime predictor predicting failed %v", err)
+					return
+				}
+
+				outputs, err := result.ReadPredictionOutput(ctx)
+				result.Close()
+				if err != nil {
+					t.Errorf("Onnxruntime predictor read prediction output failed %v", err)
+					return
+				}
+				assert.Equal(t, []float32{2 * x, 2*x + 2, 2*x + 4, 2*x + 6}, outputs[0].Data())
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestResultClosed(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+
+	// the outputs can be read several times until the result is closed
+	for i := 0; i < 2; i++ {
+		outputs, err := result.ReadPredictionOutput(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []float32{2, 4, 6, 8}, outputs[0].Data())
+	}
+
+	result.Close()
+	_, err = result.ReadPredictionOutput(ctx)
+	assert.Error(t, err)
+	result.Close()
+}
+
+// TestConcurrentErrors mixes failing and successful predictions, every call has to see its own error only
+func TestConcurrentErrors(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				// the model only accepts 4 elements
+				size := 4
+				if (g+i)%2 == 0 {
+					size = 3
+				}
+				result, err := predictor.Predict(ctx, []gotensor.Tensor{
+					gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(make([]float32, size)), gotensor.WithShape(size)),
+				})
+				if size != 4 {
+					assert.Error(t, err)
+					continue
+				}
+				if !assert.NoError(t, err) {
+					continue
+				}
+				_, err = result.ReadPredictionOutput(ctx)
+				assert.NoError(t, err)
+				result.Close()
+			}
+		}(g)
+	}
+	wg.Wait()
+}
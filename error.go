@@ -4,17 +4,61 @@ package onnxruntime
 // #include <stdlib.h>
 import "C"
 import (
+	"fmt"
 	"unsafe"
 
 	"github.com/pkg/errors"
 )
 
 /* Description: The interface for getting errors thrown by C++.
+ *              Every C++ entry point reports its errors through its own ORT_Error out-parameter,
+ *              so concurrent calls do not see the errors of each other
  * Referenced: https://github.com/c3sr/go-pytorch/blob/master/errors.go
  */
 
-// Error returned by C++
+// ErrorCode is the status code of an onnxruntime error. The codes are errors themselves,
+// so that errors.Is(err, ErrNoSuchFile) tells what went wrong
+type ErrorCode int
+
+// The codes of the errors reported by onnxruntime
+const (
+	ErrFail             ErrorCode = C.ORT_FAIL
+	ErrInvalidArgument  ErrorCode = C.ORT_INVALID_ARGUMENT
+	ErrNoSuchFile       ErrorCode = C.ORT_NO_SUCHFILE
+	ErrNoModel          ErrorCode = C.ORT_NO_MODEL
+	ErrEngineError      ErrorCode = C.ORT_ENGINE_ERROR
+	ErrRuntimeException ErrorCode = C.ORT_RUNTIME_EXCEPTION
+	ErrInvalidProtobuf  ErrorCode = C.ORT_INVALID_PROTOBUF
+	ErrModelLoaded      ErrorCode = C.ORT_MODEL_LOADED
+	ErrNotImplemented   ErrorCode = C.ORT_NOT_IMPLEMENTED
+	ErrInvalidGraph     ErrorCode = C.ORT_INVALID_GRAPH
+	ErrEPFail           ErrorCode = C.ORT_EP_FAIL
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	ErrFail:             "fail",
+	ErrInvalidArgument:  "invalid argument",
+	ErrNoSuchFile:       "no such file",
+	ErrNoModel:          "no model",
+	ErrEngineError:      "engine error",
+	ErrRuntimeException: "runtime exception",
+	ErrInvalidProtobuf:  "invalid protobuf",
+	ErrModelLoaded:      "model loaded",
+	ErrNotImplemented:   "not implemented",
+	ErrInvalidGraph:     "invalid graph",
+	ErrEPFail:           "execution provider failure",
+}
+
+func (c ErrorCode) Error() string {
+	if name, ok := errorCodeNames[c]; ok {
+		return "onnxruntime: " + name
+	}
+	return fmt.Sprintf("onnxruntime: error code %d", int(c))
+}
+
+// Error returned by C++, use errors.As to get its code
 type Error struct {
+	Code    ErrorCode
 	message string
 }
 
@@ -22,10 +66,27 @@ func (e *Error) Error() string {
 	return e.message
 }
 
+// Unwrap returns the code of the error, so that errors.Is matches it
+func (e *Error) Unwrap() error {
+	return e.Code
+}
+
+func newError(code ErrorCode, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
 func checkError(err C.ORT_Error) *Error {
 	if err.message != nil {
 		defer C.free(unsafe.Pointer(err.message))
+		code := ErrorCode(err.code)
+		if err.code == C.ORT_OK {
+			code = ErrFail
+		}
 		return &Error{
+			Code:    code,
 			message: C.GoString(err.message),
 		}
 	}
@@ -33,35 +94,22 @@ func checkError(err C.ORT_Error) *Error {
 	return nil
 }
 
-func HasError() bool {
-	return int(C.ORT_HasError()) == 1
-}
-
-func GetErrorString() string {
-	msg := C.ORT_GetErrorString()
-	if msg == nil {
-		return ""
+// takeError returns the error reported by C++ through an ORT_Error out-parameter and frees its message,
+// the out-parameter can be reused afterwards. Unlike checkError it returns an untyped nil when there is no error
+func takeError(err *C.ORT_Error) error {
+	e := checkError(*err)
+	err.message = nil
+	err.code = C.ORT_OK
+	if e != nil {
+		return e
 	}
-	return C.GoString(msg)
-}
-
-func ResetError() {
-	C.ORT_ResetError()
-}
-
-func GetError() error {
-	if !HasError() {
-		return nil
-	}
-	err := errors.New(GetErrorString())
-	ResetError()
-	return err
+	return nil
 }
 
-func PanicOnError() {
-	msg := C.ORT_GetErrorString()
-	if msg == nil {
-		return
+// recoverError turns a panic into an error, it is deferred by the exported functions so that an unexpected failure
+// of the bindings fails the call instead of the program
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = errors.Errorf("onnxruntime: unexpected panic: %v", r)
 	}
-	panic(C.GoString(msg))
 }
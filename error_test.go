@@ -0,0 +1,139 @@
+package onnxruntime
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/c3sr/dlframework/framework/options"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestErrorCodes(t *testing.T) {
+	ctx := context.Background()
+	_, err := New(ctx, options.Graph([]byte("missing.onnx")), options.Device(options.CPU_DEVICE, 0))
+	assert.True(t, errors.Is(err, ErrNoSuchFile))
+
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	input := gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4))
+	_, err = predictor.Predict(ctx, []gotensor.Tensor{input, input})
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+	assert.False(t, errors.Is(err, ErrFail))
+
+	// the errors of onnxruntime keep their code, wrapped or not
+	_, err = predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3}), gotensor.WithShape(3)),
+	})
+	var ortErr *Error
+	if assert.True(t, errors.As(errors.Wrap(err, "predict"), &ortErr)) {
+		assert.Equal(t, ErrInvalidArgument, ortErr.Code)
+		assert.NotEmpty(t, ortErr.Error())
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "garbage.onnx")
+	if err := ioutil.WriteFile(path, []byte("not a model"), 0644); err != nil {
+		t.Fatalf("failed to write %s %v", path, err)
+	}
+	_, err := New(context.Background(), options.Graph([]byte(path)), options.Device(options.CPU_DEVICE, 0))
+	assert.True(t, errors.Is(err, ErrInvalidProtobuf), "unexpected error %v", err)
+}
+
+func TestPredictInputErrors(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	for name, input := range map[string]gotensor.Tensor{
+		"type":  gotensor.New(gotensor.Of(gotensor.Int64), gotensor.WithBacking([]int64{1, 2, 3, 4}), gotensor.WithShape(4)),
+		"rank":  gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(2, 2)),
+		"shape": gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4, 5}), gotensor.WithShape(5)),
+	} {
+		result, err := predictor.Predict(ctx, []gotensor.Tensor{input})
+		assert.Nil(t, result, name)
+		assert.True(t, errors.Is(err, ErrInvalidArgument), "unexpected error for the wrong %s %v", name, err)
+	}
+}
+
+// profileFiles returns the profiles written by onnxruntime in the working directory
+func profileFiles(t *testing.T) map[string]bool {
+	paths, err := filepath.Glob("onnxruntime_*.json")
+	if err != nil {
+		t.Fatalf("failed to list the profiles %v", err)
+	}
+	res := map[string]bool{}
+	for _, path := range paths {
+		res[path] = true
+	}
+	return res
+}
+
+// newProfiledPredictor creates a profiled predictor which ran once, and returns it with the path of its profile
+func newProfiledPredictor(t *testing.T) (*Predictor, string) {
+	before := profileFiles(t)
+	predictor := newCPUPredictor(t, doubleModel(t), EnableProfiling())
+	runTestPredictor(t, predictor,
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)))
+
+	for path := range profileFiles(t) {
+		if !before[path] {
+			return predictor, path
+		}
+	}
+	predictor.Close()
+	t.Fatalf("the profile of the predictor was not found")
+	return nil, ""
+}
+
+func TestCloseProfileErrors(t *testing.T) {
+	// onnxruntime keeps writing the profile it opened, the file read on close is gone
+	predictor, path := newProfiledPredictor(t)
+	assert.NoError(t, os.Remove(path))
+	err := predictor.Close()
+	assert.True(t, errors.Is(err, ErrNoSuchFile), "unexpected error %v", err)
+	assert.NoError(t, predictor.Close())
+
+	// the file read on close is not a profile
+	predictor, path = newProfiledPredictor(t)
+	assert.NoError(t, os.Remove(path))
+	replaceFile(t, path, []byte("not a profile"))
+	err = predictor.Close()
+	var syntaxErr *json.SyntaxError
+	assert.True(t, errors.As(err, &syntaxErr), "unexpected error %v", err)
+	_, statErr := os.Stat(path)
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestErrorCodeMessages(t *testing.T) {
+	assert.Equal(t, "onnxruntime: not implemented", ErrNotImplemented.Error())
+	err := newError(ErrInvalidGraph, "graph %s is broken", "g")
+	assert.Equal(t, "graph g is broken", err.Error())
+	assert.True(t, errors.Is(err, ErrInvalidGraph))
+	assert.False(t, errors.Is(err, ErrInvalidArgument))
+}
+
+func TestCloseErrors(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	result, err := predictor.Predict(context.Background(), []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+
+	// the model is deleted by the last result
+	assert.NoError(t, predictor.Close())
+	assert.NoError(t, predictor.Close())
+	_, err = result.ReadPredictionOutput(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, result.Close())
+	assert.NoError(t, result.Close())
+}
@@ -130,7 +130,7 @@ func main() {
 
 	defer predictor.Close()
 
-	err = predictor.Predict(ctx, []gotensor.Tensor{
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
 		gotensor.New(
 			gotensor.Of(gotensor.Float32),
 			gotensor.WithBacking(imgFloats),
@@ -142,13 +142,13 @@ func main() {
 		panic(err)
 	}
 
-	outputs, err := predictor.ReadPredictionOutput(ctx)
+	defer result.Close()
+
+	samples, err := result.ReadPredictionOutputSamples(ctx)
 	if err != nil {
 		panic(err)
 	}
 
-	output := outputs[0].Data().([]float32)
-
 	labelsFileContent, err := ioutil.ReadFile(synset)
 	if err != nil {
 		panic(err)
@@ -156,15 +156,14 @@ func main() {
 
 	labels := strings.Split(string(labelsFileContent), "\n")
 
-	featuresLen := len(output) / batchSize
-
-	for i := 0; i < batchSize; i++ {
-		rprobs := make([]*dlframework.Feature, featuresLen)
-		for j := 0; j < featuresLen; j++ {
+	for _, outputs := range samples {
+		output := outputs[0].Data().([]float32)
+		rprobs := make([]*dlframework.Feature, len(output))
+		for j := range output {
 			rprobs[j] = feature.New(
 				feature.ClassificationIndex(int32(j)),
 				feature.ClassificationLabel(labels[j]),
-				feature.Probability(output[i*featuresLen+j]),
+				feature.Probability(output[j]),
 			)
 		}
 		sort.Sort(dlframework.Features(rprobs))
@@ -0,0 +1,111 @@
+package onnxruntime
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+
+	"gorgonia.org/tensor"
+)
+
+// Float16 is an IEEE 754 half-precision floating point number, stored as its raw bits.
+// It is the Go element type used for ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT16 tensors.
+type Float16 uint16
+
+// Float16Dtype is the gorgonia tensor type for tensors backed by []Float16
+var Float16Dtype = tensor.Dtype{Type: reflect.TypeOf(Float16(0))}
+
+/* Description: Convert a float32 to the nearest half-precision number (round to nearest even)
+ *              Values out of range become infinities, NaNs stay NaNs
+ */
+func NewFloat16(f float32) Float16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	if exp == 0xff {
+		if mant != 0 {
+			return Float16(sign | 0x7e00)
+		}
+		return Float16(sign | 0x7c00)
+	}
+
+	// rebias the exponent from float32 (127) to float16 (15)
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		return Float16(sign | 0x7c00)
+	}
+
+	if e <= 0 {
+		// the result is a subnormal half (or zero)
+		if e < -10 {
+			return Float16(sign)
+		}
+		mant |= 0x800000
+		shift := uint32(14 - e)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++
+		}
+		return Float16(sign | uint16(half))
+	}
+
+	half := uint32(e)<<10 | mant>>13
+	rem := mant & 0x1fff
+	// a carry out of the mantissa correctly bumps the exponent, up to infinity
+	if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return Float16(sign | uint16(half))
+}
+
+// Float32 returns the float32 value of the half-precision number, the conversion is exact
+func (h Float16) Float32() float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// normalize the subnormal half
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		mant &= 0x3ff
+		return math.Float32frombits(sign | e<<23 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// String formats the number as its float32 value
+func (h Float16) String() string {
+	return strconv.FormatFloat(float64(h.Float32()), 'g', -1, 32)
+}
+
+// Float32ToFloat16 converts a float32 slice into a newly allocated Float16 slice
+func Float32ToFloat16(data []float32) []Float16 {
+	res := make([]Float16, len(data))
+	for i, d := range data {
+		res[i] = NewFloat16(d)
+	}
+	return res
+}
+
+// Float16ToFloat32 converts a Float16 slice into a newly allocated float32 slice
+func Float16ToFloat32(data []Float16) []float32 {
+	res := make([]float32, len(data))
+	for i, d := range data {
+		res[i] = d.Float32()
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+package onnxruntime
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestFloat16Conversion(t *testing.T) {
+	cases := []struct {
+		f    float32
+		bits Float16
+	}{
+		{0, 0x0000},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{0.5, 0x3800},
+		{65504, 0x7bff},
+		{6.1035156e-05, 0x0400}, // smallest normal
+		{5.9604645e-08, 0x0001}, // smallest subnormal
+		{float32(math.Inf(1)), 0x7c00},
+		{float32(math.Inf(-1)), 0xfc00},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.bits, NewFloat16(c.f), "converting %v", c.f)
+		assert.Equal(t, c.f, c.bits.Float32(), "converting %#04x", uint16(c.bits))
+	}
+
+	// out of range values and rounding
+	assert.Equal(t, Float16(0x7c00), NewFloat16(1e6))
+	assert.Equal(t, Float16(0x0000), NewFloat16(1e-10))
+	assert.Equal(t, Float16(0x3c00), NewFloat16(1+1.0/2048))  // ties to even, rounds down
+	assert.Equal(t, Float16(0x3c02), NewFloat16(1+3.0/2048))  // ties to even, rounds up
+	assert.Equal(t, Float16(0x3c01), NewFloat16(1+1.25/1024)) // below the tie, rounds down
+	assert.True(t, math.IsNaN(float64(NewFloat16(float32(math.NaN())).Float32())))
+
+	// every finite half survives a round trip through float32
+	for i := 0; i < 1<<16; i++ {
+		h := Float16(i)
+		if h&0x7c00 == 0x7c00 {
+			continue
+		}
+		assert.Equal(t, h, NewFloat16(h.Float32()))
+	}
+
+	data := []float32{0, 1, -1.5, 1024, 0.25}
+	assert.Equal(t, data, Float16ToFloat32(Float32ToFloat16(data)))
+}
+
+func TestFloat16CastRoundTrip(t *testing.T) {
+	data := []float32{0, 1, -2.5, 0.099975586, 65504, -6.1035156e-05}
+
+	toHalf := newCPUPredictor(t, castModel(t, onnxFloat, onnxFloat16))
+	defer toHalf.Close()
+
+	outputs := runTestPredictor(t, toHalf, gotensor.New(
+		gotensor.Of(gotensor.Float32),
+		gotensor.WithBacking(data),
+		gotensor.WithShape(len(data)),
+	))
+	assert.Equal(t, Float16Dtype, outputs[0].Dtype())
+	assert.Equal(t, gotensor.Shape{len(data)}, outputs[0].Shape())
+	halves := outputs[0].Data().([]Float16)
+	assert.Equal(t, Float32ToFloat16(data), halves)
+
+	toFloat := newCPUPredictor(t, castModel(t, onnxFloat16, onnxFloat))
+	defer toFloat.Close()
+
+	outputs = runTestPredictor(t, toFloat, gotensor.New(
+		gotensor.Of(Float16Dtype),
+		gotensor.WithBacking(halves),
+		gotensor.WithShape(len(halves)),
+	))
+	assert.Equal(t, data, outputs[0].Data().([]float32))
+}
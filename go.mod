@@ -3,10 +3,12 @@ module github.com/c3sr/go-onnxruntime
 go 1.15
 
 require (
+	github.com/GeertJohan/go-sourcepath v0.0.0-20150925135350-83e8b8723a9b
 	github.com/benesch/cgosymbolizer v0.0.0-20190515212042-bec6fe6e597b
 	github.com/c3sr/config v1.0.1
 	github.com/c3sr/dlframework v1.4.4
 	github.com/c3sr/go-cupti v1.1.1
+	github.com/c3sr/image v1.0.2
 	github.com/c3sr/logger v1.0.1
 	github.com/c3sr/nvidia-smi v1.0.2
 	github.com/c3sr/tracer v1.0.5
@@ -14,6 +16,7 @@ require (
 	github.com/opentracing/opentracing-go v1.2.0
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.8.1
+	github.com/stretchr/testify v1.7.0
 	github.com/unknwon/com v1.0.1
 	gorgonia.org/tensor v0.9.14
 )
@@ -0,0 +1,168 @@
+package onnxruntime
+
+// #include "cbits/predictor.hpp"
+import "C"
+import (
+	"reflect"
+	"runtime"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+/* Description: Build the standalone C value of a Value, a tensor, a Sequence or a Map
+ *              The caller has to delete the result with deleteValue or give it to ORT_RunAddInput
+ *              The data is copied into memory owned by onnxruntime, C++ never keeps a pointer to Go memory
+ */
+func newValue(value interface{}) (C.ORT_ValueContext, error) {
+	switch v := value.(type) {
+	case Value, tensor.Tensor:
+		tv, err := toValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return newTensorValue(tv)
+	case Sequence:
+		return newSequenceValue(v)
+	case Map:
+		return newMapValue(v)
+	default:
+		return nil, errors.Errorf("unsupported value of type %T", value)
+	}
+}
+
+// valueDataType checks the value and returns the onnxruntime type of its elements
+func valueDataType(v Value) (C.ONNXTensorElementDataType, error) {
+	if err := v.check(); err != nil {
+		return C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED, err
+	}
+	elemType := reflect.TypeOf(v.Data).Elem()
+	dataType := fromType(elemType)
+	if dataType == C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED || reflect.TypeOf(v.Data) != reflect.SliceOf(elemType) {
+		return C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED, errors.Errorf("unsupported value data of type %T", v.Data)
+	}
+	return dataType, nil
+}
+
+// valuePointer returns the address of the first element of the value, nil when it has no elements
+func valuePointer(v Value) unsafe.Pointer {
+	if v.Len() == 0 {
+		return nil
+	}
+	return unsafe.Pointer(reflect.ValueOf(v.Data).Pointer())
+}
+
+func newTensorValue(v Value) (C.ORT_ValueContext, error) {
+	dataType, err := valueDataType(v)
+	if err != nil {
+		return nil, err
+	}
+
+	shape := v.Shape
+	shapePtr := cShape(shape)
+
+	var res C.ORT_ValueContext
+	var cErr C.ORT_Error
+	if dataType == C.ONNX_TENSOR_ELEMENT_DATA_TYPE_STRING {
+		cStrings := toCStrings(v.Data.([]string))
+		defer freeCStrings(cStrings)
+
+		var cStringsPtr **C.char
+		if len(cStrings) > 0 {
+			cStringsPtr = &cStrings[0]
+		}
+		res = C.ORT_NewStringTensorValue(cStringsPtr, shapePtr, C.int(len(shape)), &cErr)
+	} else {
+		res = C.ORT_NewTensorValue(valuePointer(v), shapePtr, C.int(len(shape)), dataType, &cErr)
+	}
+
+	runtime.KeepAlive(v)
+
+	if err := takeError(&cErr); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func newSequenceValue(seq Sequence) (C.ORT_ValueContext, error) {
+	if len(seq) == 0 {
+		return nil, errors.New("empty sequence input")
+	}
+
+	elements := make([]C.ORT_ValueContext, len(seq))
+	defer func() {
+		for _, element := range elements {
+			if element != nil {
+				deleteValue(element)
+			}
+		}
+	}()
+
+	for i, element := range seq {
+		value, err := newValue(element)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid sequence element %d", i)
+		}
+		elements[i] = value
+	}
+
+	// the elements are copied into the sequence, they are deleted once it is built
+	var cErr C.ORT_Error
+	res := C.ORT_NewSequenceValue(&elements[0], C.int(len(elements)), &cErr)
+	runtime.KeepAlive(seq)
+
+	if err := takeError(&cErr); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func newMapValue(m Map) (C.ORT_ValueContext, error) {
+	if m.Keys == nil || m.Values == nil {
+		return nil, errors.New("map input without keys or values")
+	}
+	if m.Keys.Shape().TotalSize() != m.Values.Shape().TotalSize() {
+		return nil, errors.New("map input with a different number of keys and values")
+	}
+
+	keys, err := newValue(m.Keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid map keys")
+	}
+	defer deleteValue(keys)
+
+	values, err := newValue(m.Values)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid map values")
+	}
+	defer deleteValue(values)
+
+	// the keys and the values are copied into the map
+	var cErr C.ORT_Error
+	res := C.ORT_NewMapValue(keys, values, &cErr)
+	runtime.KeepAlive(m)
+
+	if err := takeError(&cErr); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// deleteValue deletes a C value built by newValue, deleting can not fail
+func deleteValue(value C.ORT_ValueContext) {
+	var cErr C.ORT_Error
+	C.ORT_DeleteValue(value, &cErr)
+	takeError(&cErr)
+}
+
+// addInput appends an input to the run, which owns the C value afterwards
+func (r *Result) addInput(value interface{}) error {
+	cValue, err := newValue(value)
+	if err != nil {
+		return err
+	}
+	var cErr C.ORT_Error
+	C.ORT_RunAddInput(r.ctx, cValue, &cErr)
+	return takeError(&cErr)
+}
@@ -0,0 +1,87 @@
+package onnxruntime
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Map is an ONNX map value, Keys and Values are one dimensional tensors of the same length
+// and the i-th key is associated to the i-th value
+type Map struct {
+	Keys   tensor.Tensor
+	Values tensor.Tensor
+}
+
+// Len returns the number of entries of the map
+func (m Map) Len() int {
+	return m.Keys.Shape().TotalSize()
+}
+
+// KeyType returns the Go type of the keys of the map
+func (m Map) KeyType() reflect.Type {
+	return m.Keys.Dtype().Type
+}
+
+// ValueType returns the Go type of the values of the map
+func (m Map) ValueType() reflect.Type {
+	return m.Values.Dtype().Type
+}
+
+/* Description: Build a Map input from a Go map, e.g. a map[string]float32
+ *              The keys are sorted so that the same Go map always gives the same tensors
+ *              onnxruntime only takes string and int64 keys, a Go int has no ONNX type so convert int keys to int64
+ */
+func NewMap(goMap interface{}) (Map, error) {
+	m := reflect.ValueOf(goMap)
+	if m.Kind() != reflect.Map {
+		return Map{}, errors.Errorf("expecting a map, got %T", goMap)
+	}
+
+	keys := m.MapKeys()
+	switch m.Type().Key().Kind() {
+	case reflect.String:
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	case reflect.Int64:
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Int() < keys[j].Int() })
+	case reflect.Int:
+		return Map{}, errors.Errorf("unsupported map key type %v, convert the keys to int64", m.Type().Key())
+	default:
+		return Map{}, errors.Errorf("unsupported map key type %v", m.Type().Key())
+	}
+
+	keysData := reflect.MakeSlice(reflect.SliceOf(m.Type().Key()), len(keys), len(keys))
+	valuesData := reflect.MakeSlice(reflect.SliceOf(m.Type().Elem()), len(keys), len(keys))
+	for i, key := range keys {
+		keysData.Index(i).Set(key)
+		valuesData.Index(i).Set(m.MapIndex(key))
+	}
+
+	return Map{
+		Keys: tensor.New(
+			tensor.Of(tensor.Dtype{Type: m.Type().Key()}),
+			tensor.WithBacking(keysData.Interface()),
+			tensor.WithShape(len(keys)),
+		),
+		Values: tensor.New(
+			tensor.Of(tensor.Dtype{Type: m.Type().Elem()}),
+			tensor.WithBacking(valuesData.Interface()),
+			tensor.WithShape(len(keys)),
+		),
+	}, nil
+}
+
+/* Description: Get the entries of the map as a Go map typed after its keys and values,
+ *              e.g. a sklearn ZipMap output gives a map[int64]float32 or a map[string]float32
+ */
+func (m Map) GoMap() interface{} {
+	keys := tensorSlice(m.Keys)
+	values := tensorSlice(m.Values)
+	res := reflect.MakeMapWithSize(reflect.MapOf(m.KeyType(), m.ValueType()), keys.Len())
+	for i := 0; i < keys.Len(); i++ {
+		res.SetMapIndex(keys.Index(i), values.Index(i))
+	}
+	return res.Interface()
+}
@@ -0,0 +1,68 @@
+package onnxruntime
+
+// #include "cbits/predictor.hpp"
+import "C"
+import (
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// MemoryStats reports the memory held by the tensors of a predictor, in bytes.
+// The arena fields are not available with onnxruntime 1.7.1, which does not expose the statistics
+// of the arenas of the session, they are always zero: the bytes reserved by the arenas and
+// the memory used by the operators while running are not included in the other fields.
+type MemoryStats struct {
+	// TensorBytes is held by the inputs and the outputs of the open results and by the tensors of the bindings
+	TensorBytes int64
+	// PeakTensorBytes is the largest TensorBytes since the predictor was created
+	PeakTensorBytes int64
+	// ConvertedOutputBytes is held by the outputs converted for Go by the open results
+	ConvertedOutputBytes int64
+	// PeakConvertedOutputBytes is the largest ConvertedOutputBytes since the predictor was created
+	PeakConvertedOutputBytes int64
+	// ArenaInUseBytes is the memory of the arenas of the session in use, always zero with onnxruntime 1.7.1
+	ArenaInUseBytes int64
+	// ArenaReservedBytes is the memory reserved by the arenas of the session, always zero with onnxruntime 1.7.1
+	ArenaReservedBytes int64
+	// PeakArenaBytes is the largest ArenaInUseBytes, always zero with onnxruntime 1.7.1
+	PeakArenaBytes int64
+}
+
+// MemoryStats returns the memory currently held by the tensors of the current model of the predictor
+func (p *Predictor) MemoryStats() (_ MemoryStats, err error) {
+	defer recoverError(&err)
+
+	s, err := p.acquire()
+	if err != nil {
+		return MemoryStats{}, err
+	}
+	defer s.release()
+
+	return s.memoryStats()
+}
+
+func (s *session) memoryStats() (MemoryStats, error) {
+	var cErr C.ORT_Error
+	stats := C.ORT_PredictorMemoryStats(s.ctx, &cErr)
+	if err := takeError(&cErr); err != nil {
+		return MemoryStats{}, err
+	}
+
+	return MemoryStats{
+		TensorBytes:              int64(stats.tensor_bytes),
+		PeakTensorBytes:          int64(stats.peak_tensor_bytes),
+		ConvertedOutputBytes:     int64(stats.converted_bytes),
+		PeakConvertedOutputBytes: int64(stats.peak_converted_bytes),
+	}, nil
+}
+
+// setMemoryTags publishes the memory stats of the session as tags of span
+func (s *session) setMemoryTags(span opentracing.Span) {
+	stats, err := s.memoryStats()
+	if err != nil {
+		return
+	}
+	span.SetTag("tensor_bytes", stats.TensorBytes)
+	span.SetTag("peak_tensor_bytes", stats.PeakTensorBytes)
+	span.SetTag("converted_output_bytes", stats.ConvertedOutputBytes)
+	span.SetTag("peak_converted_output_bytes", stats.PeakConvertedOutputBytes)
+}
@@ -0,0 +1,77 @@
+package onnxruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/c3sr/tracer"
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestMemoryStats(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	stats, err := predictor.MemoryStats()
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor memory stats failed %v", err)
+	}
+	assert.Equal(t, MemoryStats{}, stats)
+
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+
+	// the result holds the 4 float32 of the output once the input is released
+	stats, err = predictor.MemoryStats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(16), stats.TensorBytes)
+	assert.Equal(t, int64(0), stats.ConvertedOutputBytes)
+
+	_, err = result.ReadPredictionOutput(ctx)
+	assert.NoError(t, err)
+	stats, err = predictor.MemoryStats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(16), stats.ConvertedOutputBytes)
+
+	result.Close()
+	stats, err = predictor.MemoryStats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), stats.TensorBytes)
+	assert.Equal(t, int64(0), stats.ConvertedOutputBytes)
+	assert.True(t, stats.PeakTensorBytes >= 16)
+	assert.Equal(t, int64(16), stats.PeakConvertedOutputBytes)
+
+	// onnxruntime 1.7.1 has no statistics of its arenas
+	assert.Equal(t, int64(0), stats.ArenaInUseBytes)
+	assert.Equal(t, int64(0), stats.ArenaReservedBytes)
+	assert.Equal(t, int64(0), stats.PeakArenaBytes)
+}
+
+func TestMemoryStatsSpanTags(t *testing.T) {
+	rec, restore := useRecordingTracer(tracer.MODEL_TRACE)
+	defer restore()
+
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	result.Close()
+
+	spans := rec.predictSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, int64(16), spans[0]["tensor_bytes"])
+		assert.Equal(t, int64(0), spans[0]["converted_output_bytes"])
+	}
+}
@@ -0,0 +1,228 @@
+package onnxruntime
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/c3sr/dlframework/framework/options"
+	gotensor "gorgonia.org/tensor"
+)
+
+/* Description: A minimal protobuf writer for ONNX models, so that the tests can build small
+ *              single purpose graphs on the fly instead of depending on downloaded fixtures
+ * Referenced: https://github.com/onnx/onnx/blob/master/onnx/onnx.proto
+ */
+
+// ONNX TensorProto.DataType values, cgo is not available in tests
+const (
+	onnxFloat    = 1
+	onnxUint8    = 2
+	onnxInt8     = 3
+	onnxUint16   = 4
+	onnxInt16    = 5
+	onnxInt32    = 6
+	onnxInt64    = 7
+	onnxString   = 8
+	onnxBool     = 9
+	onnxFloat16  = 10
+	onnxDouble   = 11
+	onnxUint32   = 12
+	onnxUint64   = 13
+	onnxBFloat16 = 16
+)
+
+type protoMessage []byte
+
+func (m protoMessage) tag(field, wireType int) protoMessage {
+	return m.uvarint(uint64(field<<3 | wireType))
+}
+
+func (m protoMessage) uvarint(v uint64) protoMessage {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(m, buf[:n]...)
+}
+
+func (m protoMessage) varintField(field int, v int64) protoMessage {
+	return m.tag(field, 0).uvarint(uint64(v))
+}
+
+func (m protoMessage) bytesField(field int, v []byte) protoMessage {
+	return append(m.tag(field, 2).uvarint(uint64(len(v))), v...)
+}
+
+func (m protoMessage) stringField(field int, v string) protoMessage {
+	return m.bytesField(field, []byte(v))
+}
+
+// tensorType builds a TypeProto for a tensor, negative dimensions are symbolic
+func tensorType(elemType int, dims ...int64) protoMessage {
+	shape := protoMessage{}
+	for _, d := range dims {
+		dim := protoMessage{}
+		if d < 0 {
+			dim = dim.stringField(2, "N")
+		} else {
+			dim = dim.varintField(1, d)
+		}
+		shape = shape.bytesField(1, dim)
+	}
+	tensor := protoMessage{}.varintField(1, int64(elemType)).bytesField(2, shape)
+	return protoMessage{}.bytesField(1, tensor)
+}
+
+// elemTensorType builds a TypeProto for a tensor without shape information
+func elemTensorType(elemType int) protoMessage {
+	return protoMessage{}.bytesField(1, protoMessage{}.varintField(1, int64(elemType)))
+}
+
+// sequenceType builds a TypeProto for a sequence of elemType
+func sequenceType(elemType protoMessage) protoMessage {
+	return protoMessage{}.bytesField(4, protoMessage{}.bytesField(1, elemType))
+}
+
+// mapType builds a TypeProto for a map from keyType to valueType
+func mapType(keyType int, valueType protoMessage) protoMessage {
+	m := protoMessage{}.varintField(1, int64(keyType)).bytesField(2, valueType)
+	return protoMessage{}.bytesField(5, m)
+}
+
+func intAttr(name string, v int64) protoMessage {
+	return protoMessage{}.stringField(1, name).varintField(3, v).varintField(20, 2)
+}
+
+func int64sAttr(name string, v ...int64) protoMessage {
+	m := protoMessage{}.stringField(1, name)
+	for _, i := range v {
+		m = m.varintField(8, i)
+	}
+	return m.varintField(20, 7)
+}
+
+func stringsAttr(name string, v ...string) protoMessage {
+	m := protoMessage{}.stringField(1, name)
+	for _, str := range v {
+		m = m.stringField(9, str)
+	}
+	return m.varintField(20, 8)
+}
+
+type testValue struct {
+	name string
+	typ  protoMessage
+}
+
+type testNode struct {
+	opType  string
+	domain  string
+	inputs  []string
+	outputs []string
+	attrs   []protoMessage
+}
+
+func (n testNode) encode() protoMessage {
+	m := protoMessage{}
+	for _, in := range n.inputs {
+		m = m.stringField(1, in)
+	}
+	for _, out := range n.outputs {
+		m = m.stringField(2, out)
+	}
+	m = m.stringField(4, n.opType)
+	for _, attr := range n.attrs {
+		m = m.bytesField(5, attr)
+	}
+	if n.domain != "" {
+		m = m.stringField(7, n.domain)
+	}
+	return m
+}
+
+// writeTestModel serializes a single graph model into a temporary directory and returns its path
+func writeTestModel(t testing.TB, nodes []testNode, inputs []testValue, outputs []testValue) string {
+	graph := protoMessage{}
+	for _, n := range nodes {
+		graph = graph.bytesField(1, n.encode())
+	}
+	graph = graph.stringField(2, "test")
+	for _, in := range inputs {
+		graph = graph.bytesField(11, protoMessage{}.stringField(1, in.name).bytesField(2, in.typ))
+	}
+	for _, out := range outputs {
+		graph = graph.bytesField(12, protoMessage{}.stringField(1, out.name).bytesField(2, out.typ))
+	}
+
+	model := protoMessage{}.
+		varintField(1, 7).
+		stringField(2, "go-onnxruntime").
+		bytesField(8, protoMessage{}.stringField(1, "").varintField(2, 13)).
+		bytesField(8, protoMessage{}.stringField(1, "ai.onnx.ml").varintField(2, 2)).
+		bytesField(7, graph)
+
+	path := filepath.Join(t.TempDir(), "model.onnx")
+	if err := ioutil.WriteFile(path, model, 0644); err != nil {
+		t.Fatalf("failed to write test model %v", err)
+	}
+	return path
+}
+
+// newCPUPredictor creates a predictor running the given model on the CPU, opts are applied after the defaults
+func newCPUPredictor(t testing.TB, modelPath string, opts ...options.Option) *Predictor {
+	ctx := context.Background()
+	defaults := options.New(options.Context(ctx),
+		options.Graph([]byte(modelPath)),
+		options.Device(options.CPU_DEVICE, 0),
+		options.BatchSize(1))
+
+	predictor, err := New(ctx, append([]options.Option{options.WithOptions(defaults)}, opts...)...)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor initialization failed %v", err)
+	}
+	return predictor
+}
+
+// runTestPredictor feeds the inputs through the predictor and returns its outputs
+func runTestPredictor(t testing.TB, predictor *Predictor, inputs ...gotensor.Tensor) []gotensor.Tensor {
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, inputs)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	outputs, err := result.ReadPredictionOutput(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+	return outputs
+}
+
+// castModel returns a model casting its input of type from into type to
+func castModel(t testing.TB, from, to int) string {
+	return writeTestModel(t,
+		[]testNode{{opType: "Cast", inputs: []string{"x"}, outputs: []string{"y"}, attrs: []protoMessage{intAttr("to", int64(to))}}},
+		[]testValue{{"x", tensorType(from, -1)}},
+		[]testValue{{"y", tensorType(to, -1)}},
+	)
+}
+
+// identityModel returns a model passing its input of type elemType through unchanged
+func identityModel(t testing.TB, elemType int) string {
+	return writeTestModel(t,
+		[]testNode{{opType: "Identity", inputs: []string{"x"}, outputs: []string{"y"}}},
+		[]testValue{{"x", tensorType(elemType, -1)}},
+		[]testValue{{"y", tensorType(elemType, -1)}},
+	)
+}
+
+// identityModelAnyShape returns an identity model whose input has no declared shape, so it accepts any rank
+func identityModelAnyShape(t testing.TB, elemType int) string {
+	return writeTestModel(t,
+		[]testNode{{opType: "Identity", inputs: []string{"x"}, outputs: []string{"y"}}},
+		[]testValue{{"x", elemTensorType(elemType)}},
+		[]testValue{{"y", elemTensorType(elemType)}},
+	)
+}
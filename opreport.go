@@ -0,0 +1,205 @@
+package onnxruntime
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// GroupBy chooses what the kernels of an OpReport are grouped by
+type GroupBy int
+
+const (
+	// GroupByOpType groups the kernels of all the nodes running the same operator
+	GroupByOpType GroupBy = iota
+	// GroupByNode groups the kernels of each node
+	GroupByNode
+)
+
+// SortBy chooses the order of the stats of an OpReport, the durations and the count sort in decreasing order
+type SortBy int
+
+const (
+	SortByTotal SortBy = iota
+	SortByMean
+	SortByP50
+	SortByP99
+	SortByCount
+	SortByName
+)
+
+// OpStats is the timing of the kernels of an op type or a node across the runs of a profile
+type OpStats struct {
+	// Name is the op type or the name of the node
+	Name   string
+	OpType string
+	Count  int
+	Total  time.Duration
+	Mean   time.Duration
+	P50    time.Duration
+	P99    time.Duration
+	// Percent is the share of the run time of the model spent in the kernels, 0 when the profile has no run
+	Percent float64
+}
+
+// OpReport aggregates the kernel times of a profile, to find the operators or the layers taking the most time
+type OpReport struct {
+	GroupBy GroupBy
+	// Runs is the number of runs of the model in the profile and RunTime their total duration
+	Runs    int
+	RunTime time.Duration
+	Stats   []OpStats
+}
+
+// NewOpReport aggregates the kernel events of the trace of an onnxruntime profile, sorted by SortByTotal
+func NewOpReport(t *Trace, groupBy GroupBy) (*OpReport, error) {
+	report := &OpReport{GroupBy: groupBy}
+
+	var keys []string
+	durations := map[string][]time.Duration{}
+	opTypes := map[string]string{}
+	for _, event := range t.TraceEvents {
+		if event.Name == "model_run" {
+			report.Runs++
+			report.RunTime += time.Duration(event.Duration) * time.Microsecond
+			continue
+		}
+		if event.Category != "Node" || !strings.HasSuffix(event.Name, "_kernel_time") {
+			continue
+		}
+		node, err := nodeProfile(event)
+		if err != nil {
+			return nil, err
+		}
+		key := node.OpType
+		if groupBy == GroupByNode {
+			key = node.Name
+		}
+		if _, ok := durations[key]; !ok {
+			keys = append(keys, key)
+			opTypes[key] = node.OpType
+		}
+		durations[key] = append(durations[key], node.Duration)
+	}
+
+	report.Stats = make([]OpStats, len(keys))
+	for i, key := range keys {
+		d := durations[key]
+		sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+
+		stats := OpStats{Name: key, OpType: opTypes[key], Count: len(d)}
+		for _, duration := range d {
+			stats.Total += duration
+		}
+		stats.Mean = stats.Total / time.Duration(len(d))
+		stats.P50 = percentile(d, 50)
+		stats.P99 = percentile(d, 99)
+		if report.RunTime > 0 {
+			stats.Percent = 100 * float64(stats.Total) / float64(report.RunTime)
+		}
+		report.Stats[i] = stats
+	}
+
+	report.Sort(SortByTotal)
+	return report, nil
+}
+
+// percentile returns the nearest rank percentile of the sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Sort orders the stats of the report, the ties are ordered by name
+func (r *OpReport) Sort(by SortBy) {
+	key := func(s OpStats) int64 {
+		switch by {
+		case SortByMean:
+			return int64(s.Mean)
+		case SortByP50:
+			return int64(s.P50)
+		case SortByP99:
+			return int64(s.P99)
+		case SortByCount:
+			return int64(s.Count)
+		case SortByName:
+			return 0
+		default:
+			return int64(s.Total)
+		}
+	}
+	sort.SliceStable(r.Stats, func(i, j int) bool {
+		a, b := key(r.Stats[i]), key(r.Stats[j])
+		if a != b {
+			return a > b
+		}
+		return r.Stats[i].Name < r.Stats[j].Name
+	})
+}
+
+func (r *OpReport) header() []string {
+	if r.GroupBy == GroupByNode {
+		return []string{"node", "op_type"}
+	}
+	return []string{"op_type"}
+}
+
+func (r *OpReport) names(s OpStats) []string {
+	if r.GroupBy == GroupByNode {
+		return []string{s.Name, s.OpType}
+	}
+	return []string{s.Name}
+}
+
+// WriteTable writes the report as a text table aligned with spaces
+func (r *OpReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	header := append(r.header(), "count", "total", "mean", "p50", "p99", "%run")
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+	for _, s := range r.Stats {
+		row := append(r.names(s),
+			strconv.Itoa(s.Count),
+			s.Total.String(),
+			s.Mean.String(),
+			s.P50.String(),
+			s.P99.String(),
+			strconv.FormatFloat(s.Percent, 'f', 2, 64),
+		)
+		fmt.Fprintln(tw, strings.Join(row, "\t")+"\t")
+	}
+	return tw.Flush()
+}
+
+// WriteCSV writes the report as CSV with a header line, the durations are in microseconds like in the profile
+func (r *OpReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(r.header(), "count", "total_us", "mean_us", "p50_us", "p99_us", "percent")); err != nil {
+		return err
+	}
+	micros := func(d time.Duration) string {
+		return strconv.FormatInt(d.Microseconds(), 10)
+	}
+	for _, s := range r.Stats {
+		row := append(r.names(s),
+			strconv.Itoa(s.Count),
+			micros(s.Total),
+			micros(s.Mean),
+			micros(s.P50),
+			micros(s.P99),
+			strconv.FormatFloat(s.Percent, 'f', 2, 64),
+		)
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
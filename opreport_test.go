@@ -0,0 +1,115 @@
+package onnxruntime
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+const opReportProfile = `[
+	{"cat": "Session", "name": "model_run", "ts": 0, "dur": 100, "tid": 1},
+	{"cat": "Node", "name": "conv1_kernel_time", "ts": 1, "dur": 30, "tid": 1, "args": {"op_name": "Conv"}},
+	{"cat": "Node", "name": "relu1_kernel_time", "ts": 31, "dur": 5, "tid": 1, "args": {"op_name": "Relu"}},
+	{"cat": "Node", "name": "conv2_kernel_time", "ts": 36, "dur": 10, "tid": 1, "args": {"op_name": "Conv"}},
+	{"cat": "Session", "name": "model_run", "ts": 200, "dur": 100, "tid": 1},
+	{"cat": "Node", "name": "conv1_kernel_time", "ts": 201, "dur": 50, "tid": 1, "args": {"op_name": "Conv"}},
+	{"cat": "Node", "name": "relu1_kernel_time", "ts": 251, "dur": 5, "tid": 1, "args": {"op_name": "Relu"}},
+	{"cat": "Node", "name": "conv2_kernel_time", "ts": 256, "dur": 10, "tid": 1, "args": {"op_name": "Conv"}}
+]`
+
+func TestOpReport(t *testing.T) {
+	trace, err := NewTrace(opReportProfile, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the trace %v", err)
+	}
+
+	report, err := NewOpReport(trace, GroupByOpType)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Runs)
+	assert.Equal(t, 200*time.Microsecond, report.RunTime)
+	assert.Equal(t, []OpStats{
+		{
+			Name: "Conv", OpType: "Conv", Count: 4,
+			Total: 100 * time.Microsecond, Mean: 25 * time.Microsecond,
+			P50: 10 * time.Microsecond, P99: 50 * time.Microsecond,
+			Percent: 50,
+		},
+		{
+			Name: "Relu", OpType: "Relu", Count: 2,
+			Total: 10 * time.Microsecond, Mean: 5 * time.Microsecond,
+			P50: 5 * time.Microsecond, P99: 5 * time.Microsecond,
+			Percent: 5,
+		},
+	}, report.Stats)
+
+	report, err = NewOpReport(trace, GroupByNode)
+	assert.NoError(t, err)
+	if assert.Len(t, report.Stats, 3) {
+		assert.Equal(t, "conv1", report.Stats[0].Name)
+		assert.Equal(t, "Conv", report.Stats[0].OpType)
+		assert.Equal(t, 40*time.Microsecond, report.Stats[0].Mean)
+		assert.Equal(t, 30*time.Microsecond, report.Stats[0].P50)
+	}
+
+	report.Sort(SortByName)
+	assert.Equal(t, "conv1", report.Stats[0].Name)
+	assert.Equal(t, "conv2", report.Stats[1].Name)
+	assert.Equal(t, "relu1", report.Stats[2].Name)
+
+	report.Sort(SortByP99)
+	assert.Equal(t, "conv1", report.Stats[0].Name)
+	assert.Equal(t, "conv2", report.Stats[1].Name)
+}
+
+func TestOpReportWrite(t *testing.T) {
+	trace, err := NewTrace(opReportProfile, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the trace %v", err)
+	}
+	report, err := NewOpReport(trace, GroupByNode)
+	if err != nil {
+		t.Fatalf("failed to aggregate the trace %v", err)
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, report.WriteCSV(&buf))
+	assert.Equal(t, strings.Join([]string{
+		"node,op_type,count,total_us,mean_us,p50_us,p99_us,percent",
+		"conv1,Conv,2,80,40,30,50,40.00",
+		"conv2,Conv,2,20,10,10,10,10.00",
+		"relu1,Relu,2,10,5,5,5,5.00",
+		"",
+	}, "\n"), buf.String())
+
+	buf.Reset()
+	assert.NoError(t, report.WriteTable(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 4) {
+		assert.Equal(t, []string{"node", "op_type", "count", "total", "mean", "p50", "p99", "%run"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"conv1", "Conv", "2", "80µs", "40µs", "30µs", "50µs", "40.00"}, strings.Fields(lines[1]))
+	}
+}
+
+func TestPredictorOpReport(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t), EnableProfiling())
+	defer predictor.Close()
+
+	for i := 0; i < 3; i++ {
+		runTestPredictor(t, predictor,
+			gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)))
+	}
+
+	report, err := predictor.OpReport(GroupByOpType)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor op report failed %v", err)
+	}
+	assert.Equal(t, 3, report.Runs)
+	if assert.Len(t, report.Stats, 1) {
+		assert.Equal(t, "Add", report.Stats[0].Name)
+		assert.Equal(t, 3, report.Stats[0].Count)
+	}
+}
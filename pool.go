@@ -0,0 +1,316 @@
+package onnxruntime
+
+import (
+	"context"
+	"sync"
+
+	"github.com/c3sr/dlframework/framework/options"
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Pool spreads the predictions over several predictors of the same model. Each prediction is
+// dispatched to an idle predictor, and waits for one to become idle when they are all busy.
+// A predictor of the pool runs one prediction at a time, so its profile splits into the
+// predictions as it does for a predictor used sequentially.
+// The results returned by the pool must be closed before the pool.
+type Pool struct {
+	opts []options.Option
+
+	mu      sync.Mutex
+	members []*poolMember
+	idle    []*poolMember
+	// retired holds the members removed by Resize which are not closed yet
+	retired map[*poolMember]struct{}
+	// size is the size asked by the last Resize, and pending counts the predictors being built by Resize
+	size    int
+	pending int
+	// wake is closed and replaced whenever a predictor becomes idle or the pool is closed
+	wake    chan struct{}
+	running sync.WaitGroup
+	closed  bool
+}
+
+type poolMember struct {
+	predictor *Predictor
+	busy      bool
+	// results counts the results of the predictor which are still open
+	results int
+	// retired members are removed from the pool by Resize, they are closed once they are not used anymore
+	retired bool
+}
+
+// NewPool creates a pool of size predictors, all built from the same options
+func NewPool(ctx context.Context, size int, opts ...options.Option) (*Pool, error) {
+	if size < 1 {
+		return nil, errors.Errorf("invalid pool size %d", size)
+	}
+
+	p := &Pool{
+		opts:    opts,
+		retired: map[*poolMember]struct{}{},
+		wake:    make(chan struct{}),
+	}
+	if err := p.Resize(ctx, size); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Size returns the number of predictors of the pool
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.members)
+}
+
+// Resize grows or shrinks the pool to size predictors. The predictors removed from the pool finish
+// their running prediction and are closed once their results are closed.
+func (p *Pool) Resize(ctx context.Context, size int) error {
+	if size < 1 {
+		return errors.Errorf("invalid pool size %d", size)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return errors.New("pool is closed")
+	}
+	p.size = size
+	// the predictors being built count in the size, so that concurrent calls do not build too many
+	missing := size - len(p.members) - p.pending
+	if missing > 0 {
+		p.pending += missing
+	}
+	retired := []*Predictor{}
+	for len(p.members) > size {
+		// idle members go first, the last of the idle slice is the next one to be used
+		var m *poolMember
+		if len(p.idle) > 0 {
+			m = p.idle[0]
+			p.idle = p.idle[1:]
+		} else {
+			m = p.members[len(p.members)-1]
+		}
+		p.removeMember(m)
+		m.retired = true
+		p.retired[m] = struct{}{}
+		if p.done(m) {
+			retired = append(retired, m.predictor)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, predictor := range retired {
+		predictor.Close()
+	}
+
+	// the predictors are built outside of the lock, loading a model takes time
+	for i := 0; i < missing; i++ {
+		predictor, err := New(ctx, p.opts...)
+		if err != nil {
+			if predictor != nil {
+				predictor.Close()
+			}
+			p.mu.Lock()
+			p.pending -= missing - i
+			p.mu.Unlock()
+			return errors.Wrap(err, "failed to create a predictor of the pool")
+		}
+
+		p.mu.Lock()
+		p.pending--
+		if p.closed {
+			p.pending -= missing - i - 1
+			p.mu.Unlock()
+			predictor.Close()
+			return errors.New("pool is closed")
+		}
+		// a later Resize may have shrunk the pool while the predictor was built
+		if len(p.members) >= p.size {
+			p.mu.Unlock()
+			predictor.Close()
+			continue
+		}
+		m := &poolMember{predictor: predictor}
+		p.members = append(p.members, m)
+		p.idle = append(p.idle, m)
+		p.notify()
+		p.mu.Unlock()
+	}
+
+	return nil
+}
+
+// removeMember removes m from the members, p.mu must be held
+func (p *Pool) removeMember(m *poolMember) {
+	for i, member := range p.members {
+		if member == m {
+			p.members = append(p.members[:i], p.members[i+1:]...)
+			return
+		}
+	}
+}
+
+// done reports whether the retired member m is not used anymore and removes it from the retired members,
+// the caller then has to close its predictor. p.mu must be held
+func (p *Pool) done(m *poolMember) bool {
+	if _, ok := p.retired[m]; !ok || m.busy || m.results > 0 {
+		return false
+	}
+	delete(p.retired, m)
+	return true
+}
+
+// notify wakes up the predictions waiting for an idle predictor, p.mu must be held
+func (p *Pool) notify() {
+	close(p.wake)
+	p.wake = make(chan struct{})
+}
+
+// acquire waits for an idle predictor, or for ctx to be done
+func (p *Pool) acquire(ctx context.Context) (*poolMember, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errors.New("pool is closed")
+		}
+		if n := len(p.idle); n > 0 {
+			m := p.idle[n-1]
+			p.idle = p.idle[:n-1]
+			m.busy = true
+			p.running.Add(1)
+			p.mu.Unlock()
+			return m, nil
+		}
+		wake := p.wake
+		p.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release gives a predictor back to the pool once its prediction is done
+func (p *Pool) release(m *poolMember) {
+	p.mu.Lock()
+	m.busy = false
+	closing := p.done(m)
+	if !m.retired && !p.closed {
+		p.idle = append(p.idle, m)
+		p.notify()
+	}
+	p.mu.Unlock()
+
+	if closing {
+		m.predictor.Close()
+	}
+	p.running.Done()
+}
+
+// closeResult is called when a result of m is closed
+func (p *Pool) closeResult(m *poolMember) {
+	p.mu.Lock()
+	m.results--
+	closing := p.done(m)
+	p.mu.Unlock()
+
+	if closing {
+		m.predictor.Close()
+	}
+}
+
+// Predict runs the model on tensors with an idle predictor of the pool, see Predictor.Predict
+func (p *Pool) Predict(ctx context.Context, inputs []tensor.Tensor) (*Result, error) {
+	values := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		values[i] = input
+	}
+	return p.PredictValues(ctx, values)
+}
+
+// PredictSlices runs the model on plain Go slices with an idle predictor of the pool, see Predictor.PredictSlices
+func (p *Pool) PredictSlices(ctx context.Context, inputs []Value) (*Result, error) {
+	values := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		values[i] = input
+	}
+	return p.PredictValues(ctx, values)
+}
+
+// PredictValues runs the model with an idle predictor of the pool, see Predictor.PredictValues.
+// It waits for a predictor to become idle when they are all busy, until ctx is done.
+func (p *Pool) PredictValues(ctx context.Context, inputs []interface{}) (*Result, error) {
+	m, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(m)
+
+	res, err := m.predictor.PredictValues(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	m.results++
+	p.mu.Unlock()
+	res.onClose = func() {
+		p.closeResult(m)
+	}
+
+	return res, nil
+}
+
+// Close waits for the running predictions and closes all the predictors of the pool,
+// which publish their traces. The predictions waiting for an idle predictor fail.
+// The error is the first one returned by Predictor.Close.
+func (p *Pool) Close() error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.notify()
+	p.mu.Unlock()
+
+	p.running.Wait()
+
+	p.mu.Lock()
+	members := p.members
+	for m := range p.retired {
+		members = append(members, m)
+	}
+	p.members = nil
+	p.idle = nil
+	p.retired = map[*poolMember]struct{}{}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(members))
+	for i, m := range members {
+		wg.Add(1)
+		go func(i int, predictor *Predictor) {
+			defer wg.Done()
+			errs[i] = predictor.Close()
+		}(i, m.predictor)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
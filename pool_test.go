@@ -0,0 +1,157 @@
+package onnxruntime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/c3sr/dlframework/framework/options"
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func newCPUPool(t testing.TB, size int, modelPath string) *Pool {
+	ctx := context.Background()
+	opts := options.New(options.Context(ctx),
+		options.Graph([]byte(modelPath)),
+		options.Device(options.CPU_DEVICE, 0),
+		options.BatchSize(1))
+
+	pool, err := NewPool(ctx, size, options.WithOptions(opts))
+	if err != nil {
+		t.Fatalf("Onnxruntime pool initialization failed %v", err)
+	}
+	return pool
+}
+
+func predictDouble(t testing.TB, pool *Pool, x float32) {
+	ctx := context.Background()
+	result, err := pool.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{x, x, x, x}), gotensor.WithShape(4)),
+	})
+	if err != nil {
+		t.Errorf("Onnxruntime pool predicting failed %v", err)
+		return
+	}
+	defer result.Close()
+
+	outputs, err := result.ReadPredictionOutput(ctx)
+	if err != nil {
+		t.Errorf("Onnxruntime pool read prediction output failed %v", err)
+		return
+	}
+	assert.Equal(t, []float32{2 * x, 2 * x, 2 * x, 2 * x}, outputs[0].Data())
+}
+
+func TestPoolConcurrentPredict(t *testing.T) {
+	pool := newCPUPool(t, 3, doubleModel(t))
+	defer pool.Close()
+	assert.Equal(t, 3, pool.Size())
+
+	var wg sync.WaitGroup
+	for g := 0; g < 12; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				predictDouble(t, pool, float32(g*20+i))
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestPoolBackPressure(t *testing.T) {
+	pool := newCPUPool(t, 1, doubleModel(t))
+	defer pool.Close()
+
+	// hold the only predictor as if a prediction was running
+	m, err := pool.acquire(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = pool.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(make([]float32, 4)), gotensor.WithShape(4)),
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		predictDouble(t, pool, 1)
+	}()
+	pool.release(m)
+	<-done
+}
+
+func TestPoolResize(t *testing.T) {
+	pool := newCPUPool(t, 2, doubleModel(t))
+	defer pool.Close()
+
+	ctx := context.Background()
+	assert.NoError(t, pool.Resize(ctx, 4))
+	assert.Equal(t, 4, pool.Size())
+	predictDouble(t, pool, 1)
+
+	// a predictor removed from the pool stays open until its results are closed
+	result, err := pool.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime pool predicting failed %v", err)
+	}
+	assert.NoError(t, pool.Resize(ctx, 1))
+	assert.Equal(t, 1, pool.Size())
+
+	outputs, err := result.ReadPredictionOutput(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{2, 4, 6, 8}, outputs[0].Data())
+	result.Close()
+
+	predictDouble(t, pool, 3)
+	assert.Error(t, pool.Resize(ctx, 0))
+}
+
+func TestPoolConcurrentResize(t *testing.T) {
+	pool := newCPUPool(t, 1, doubleModel(t))
+	defer pool.Close()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, pool.Resize(ctx, 3))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 3, pool.Size())
+
+	// the pool shrinks to the last size even if the predictors of a grow are still being built
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, pool.Resize(ctx, 6))
+	}()
+	assert.NoError(t, pool.Resize(ctx, 2))
+	wg.Wait()
+	assert.True(t, pool.Size() == 2 || pool.Size() == 6, "unexpected pool size %d", pool.Size())
+	assert.NoError(t, pool.Resize(ctx, 2))
+	assert.Equal(t, 2, pool.Size())
+	predictDouble(t, pool, 1)
+}
+
+func TestPoolClosed(t *testing.T) {
+	pool := newCPUPool(t, 1, doubleModel(t))
+	pool.Close()
+
+	_, err := pool.Predict(context.Background(), []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(make([]float32, 4)), gotensor.WithShape(4)),
+	})
+	assert.Error(t, err)
+	assert.Error(t, pool.Resize(context.Background(), 2))
+	pool.Close()
+}
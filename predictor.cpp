@@ -11,7 +11,16 @@
 #include <cstring>
 #include <cstdlib>
 #include <cstdio>
+#include <memory>
+#include <map>
+#include <atomic>
 #include <onnxruntime_cxx_api.h>
+#if defined(__APPLE__)
+#include <pthread.h>
+#else
+#include <sys/syscall.h>
+#include <unistd.h>
+#endif
 
 #ifdef ORT_WITH_GPU
 #include <cuda_provider_factory.h>
@@ -19,17 +28,23 @@
 
 using std::string;
 
+/* Description: Count the bytes currently held by some memory, and the largest count seen */
+struct MemoryCounter {
+  void Add(int64_t bytes) {
+    int64_t current = current_ += bytes;
+    int64_t peak = peak_.load();
+    while (current > peak && !peak_.compare_exchange_weak(peak, current)) {
+    }
+  }
+  std::atomic<int64_t> current_{0};
+  std::atomic<int64_t> peak_{0};
+};
+
 /* Description: The structure to handle the predictor for onnxruntime
- * Note: Call ConvertOutput before you want to read the outputs
+ * Note: The inputs and the outputs of a prediction belong to a Run, so that predictions can run concurrently
  */ 
 struct Predictor {
   Predictor(const string &model_file, ORT_DeviceKind device, bool enable_trace, int device_id);
-  ~Predictor();
-  void Predict(void);
-  void ConvertOutput(void);
-  void AddOutput(Ort::Value&);
-  void Clear(void);
-  void *ConvertTensorToPointer(Ort::Value&, size_t);
   void EndProfiling(void);
   struct Onnxruntime_Env {
     Ort::Env env_;
@@ -66,11 +81,30 @@ struct Predictor {
   Ort::AllocatorWithDefaultOptions allocator_;
   string profile_filename_;
   std::vector<const char*> input_node_;
-  std::vector<Ort::Value> input_;
   std::vector<const char*> output_node_;
+  bool enable_trace_;
+  // the tensors of the runs and the bindings allocated by onnxruntime, and the outputs converted for Go
+  MemoryCounter tensor_memory_;
+  MemoryCounter converted_memory_;
+};
+
+/* Description: The structure holding the inputs and the outputs of one prediction
+ *              Ort::Session::Run is thread-safe, so runs of the same predictor can be used concurrently
+ * Note: Call ConvertOutput before you want to read the outputs
+ */
+struct Run {
+  Run(Predictor *predictor) : predictor_(predictor) {}
+  ~Run();
+  void AddInput(Ort::Value);
+  void Predict(void);
+  void ConvertOutput(void);
+  void ClearConvertedOutput(void);
+  Predictor *predictor_;
+  // the options of the session run, Terminate sets their flag from another thread to stop the run
+  Ort::RunOptions run_options_;
+  std::vector<Ort::Value> input_;
   std::vector<Ort::Value> output_;
   std::vector<ORT_Value> converted_output_;
-  bool enable_trace_;
 };
 
 /* Description: Follow the sample given in onnxruntime to initialize the predictor
@@ -99,41 +133,140 @@ Predictor::Predictor(const string &model_file, ORT_DeviceKind device, bool enabl
 
 }
 
-/* Description: clean up the predictor for next prediction */
-void Predictor::Clear() {
+/* Description: Free the memory of a converted output, including the elements of sequences and maps
+ *              The data of a numeric tensor converted as a view is owned by onnxruntime
+ */
+static void FreeValue(ORT_Value &value, bool view = false) {
+  for (size_t i = 0; i < value.elements_len; i++) {
+    FreeValue(value.elements_ptr[i]);
+  }
+  if (!view || value.otype == ONNX_TENSOR_ELEMENT_DATA_TYPE_STRING) {
+    free(value.data_ptr);
+  }
+  free((void*) value.shape_ptr);
+  free((void*) value.offsets_ptr);
+  free((void*) value.elements_ptr);
+  value.data_ptr = nullptr;
+  value.shape_ptr = nullptr;
+  value.offsets_ptr = nullptr;
+  value.elements_ptr = nullptr;
+  value.elements_len = 0;
+}
+
+/* Description: Get the size in bytes of an element of the numeric data types Go can provide */
+static size_t ElementSize(ONNXTensorElementDataType dtype) {
+  switch (dtype) {
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT:
+      return sizeof(float);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT8:
+      return sizeof(uint8_t);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_INT8:
+      return sizeof(int8_t);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT16:
+      return sizeof(uint16_t);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_INT16:
+      return sizeof(int16_t);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_INT32:
+      return sizeof(int32_t);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_INT64:
+      return sizeof(int64_t);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_BOOL:
+      return sizeof(bool);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_DOUBLE:
+      return sizeof(double);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT32:
+      return sizeof(uint32_t);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT64:
+      return sizeof(uint64_t);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT16:
+      return sizeof(Ort::Float16_t);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_BFLOAT16:
+      return sizeof(Ort::BFloat16_t);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED:
+      throw Ort::Exception(std::string("undefined data type detected in ElementSize."), ORT_NOT_IMPLEMENTED);
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_STRING:
+      throw Ort::Exception(std::string("string tensors need to be created through CreateStringTensorValue."), ORT_INVALID_ARGUMENT);
+    default: // onnxruntime: COMPLEX64, COMPLEX128
+      throw Ort::Exception(std::string("unsupported data type detected in ElementSize."), ORT_NOT_IMPLEMENTED);
+  }
+}
+
+/* Description: Get the bytes held by the data of a tensor, other values count for nothing */
+static int64_t TensorBytes(Ort::Value &value) {
+  if (static_cast<OrtValue*>(value) == nullptr || !value.IsTensor()) {
+    return 0;
+  }
+  auto tensor_info = value.GetTensorTypeAndShapeInfo();
+  if (tensor_info.GetElementType() == ONNX_TENSOR_ELEMENT_DATA_TYPE_STRING) {
+    return static_cast<int64_t>(value.GetStringTensorDataLength());
+  }
+  return static_cast<int64_t>(tensor_info.GetElementCount() * ElementSize(tensor_info.GetElementType()));
+}
+
+static int64_t TensorBytes(std::vector<Ort::Value> &values) {
+  int64_t res = 0;
+  for (auto &value : values) {
+    res += TensorBytes(value);
+  }
+  return res;
+}
+
+/* Description: Get the bytes held by the data of a converted value, including the elements of sequences and maps */
+static int64_t ConvertedBytes(const ORT_Value &value) {
+  int64_t res = 0;
+  if (value.vtype == ONNX_TYPE_TENSOR && value.data_ptr != nullptr) {
+    size_t size = 1;
+    for (size_t i = 0; i < value.shape_len; i++) {
+      size *= value.shape_ptr[i];
+    }
+    if (value.otype == ONNX_TENSOR_ELEMENT_DATA_TYPE_STRING) {
+      res += static_cast<int64_t>(value.offsets_ptr[size]);
+    } else {
+      res += static_cast<int64_t>(size * ElementSize(value.otype));
+    }
+  }
+  for (size_t i = 0; i < value.elements_len; i++) {
+    res += ConvertedBytes(value.elements_ptr[i]);
+  }
+  return res;
+}
+
+/* Description: Free the converted outputs of the run */
+void Run::ClearConvertedOutput(void) {
   for(size_t i = 0; i < converted_output_.size(); i++) {
-    free(converted_output_[i].data_ptr);
-    free((void*) converted_output_[i].shape_ptr);
-    converted_output_[i].data_ptr = nullptr;
-    converted_output_[i].shape_ptr = nullptr;
+    predictor_->converted_memory_.Add(-ConvertedBytes(converted_output_[i]));
+    FreeValue(converted_output_[i]);
   }
   converted_output_.clear();
-  input_.clear();
 }
 
-/* Description: Destructor of the predictor to clean up dynamic allocated momory */
-Predictor::~Predictor() {
-  Clear();
+/* Description: Destructor of the run to clean up dynamic allocated momory */
+Run::~Run() {
+  ClearConvertedOutput();
+  predictor_->tensor_memory_.Add(-TensorBytes(input_) - TensorBytes(output_));
 }
 
 /* Description: Do the inference in onnxruntime */
-void Predictor::Predict(void) {
+void Run::Predict(void) {
   // check invalid dims size
-  if (input_.size() != input_node_.size()) {
-    throw std::runtime_error(std::string("Invalid number of input tensor in Predictor::Predict."));
+  if (input_.size() != predictor_->input_node_.size()) {
+    throw Ort::Exception(std::string("Invalid number of input tensor in Run::Predict."), ORT_INVALID_ARGUMENT);
   }
 
-  output_ = session_.Run(Ort::RunOptions{nullptr}, input_node_.data(), input_.data(),
-                         input_.size(), output_node_.data(), output_node_.size());
-
+  output_ = predictor_->session_.Run(run_options_, predictor_->input_node_.data(), input_.data(),
+                                     input_.size(), predictor_->output_node_.data(), predictor_->output_node_.size());
+  predictor_->tensor_memory_.Add(TensorBytes(output_));
+  // the inputs are not needed anymore, their memory is given back right away
+  predictor_->tensor_memory_.Add(-TensorBytes(input_));
+  input_.clear();
 }
 
 /* Description: Convert Ort::Value to an array pointed by the pointer */
-void *Predictor::ConvertTensorToPointer(Ort::Value& value, size_t size) {
+static void *ConvertTensorToPointer(Ort::Value& value, size_t size) {
   void *res = nullptr;
   switch (value.GetTensorTypeAndShapeInfo().GetElementType()) {
     case ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED:
-      throw std::runtime_error(std::string("undefined data type detected in ConvertTensorToPointer."));
+      throw Ort::Exception(std::string("undefined data type detected in ConvertTensorToPointer."), ORT_NOT_IMPLEMENTED);
     break;
     case ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT:
       res = (void*) malloc(sizeof(float) * size);
@@ -179,48 +312,122 @@ void *Predictor::ConvertTensorToPointer(Ort::Value& value, size_t size) {
       res = (void*) malloc(sizeof(uint64_t) * size);
       memcpy(res, value.GetTensorMutableData<uint64_t>(), sizeof(uint64_t) * size);
     break;
-    default: // c++: FLOAT16; onnxruntime: COMPLEX64, COMPLEX128, BFLOAT16; TODO: Implement String method
-      throw std::runtime_error(std::string("unsupported data type detected in Predictor::ConvertTensorToPointer."));
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT16:
+      res = (void*) malloc(sizeof(Ort::Float16_t) * size);
+      memcpy(res, value.GetTensorMutableData<Ort::Float16_t>(), sizeof(Ort::Float16_t) * size);
+    break;
+    case ONNX_TENSOR_ELEMENT_DATA_TYPE_BFLOAT16:
+      res = (void*) malloc(sizeof(Ort::BFloat16_t) * size);
+      memcpy(res, value.GetTensorMutableData<Ort::BFloat16_t>(), sizeof(Ort::BFloat16_t) * size);
+    break;
+    default: // onnxruntime: COMPLEX64, COMPLEX128; strings are handled by ConvertStringTensorToPointer
+      throw Ort::Exception(std::string("unsupported data type detected in ConvertTensorToPointer."), ORT_NOT_IMPLEMENTED);
+  }
+  return res;
+}
+
+/* Description: Convert a string Ort::Value to the concatenation of its strings
+ *              The start of the i-th string is stored in offsets[i], offsets[size] is the total length
+ */
+static void *ConvertStringTensorToPointer(Ort::Value& value, size_t size, size_t **offsets_ptr) {
+  size_t length = value.GetStringTensorDataLength();
+  size_t *offsets = (size_t*) malloc(sizeof(size_t) * (size + 1));
+  // keep a valid pointer even when all the strings are empty
+  void *res = malloc(length > 0 ? length : 1);
+  if (size > 0) {
+    value.GetStringTensorContent(res, length, offsets, size);
   }
+  offsets[size] = length;
+  *offsets_ptr = offsets;
   return res;
 }
 
-/* Description: The helper function when calling ConvertOutput for converting all outputs into array form
- *              Since Ort::Value can be a tensor, a map or a sequence, we need to decompose it by recursion
+/* Description: The helper function when calling ConvertOutput for converting an output into array form
+ *              Since Ort::Value can be a tensor, a map or a sequence, the structure is kept by recursion:
+ *              the elements of a sequence, or the keys and the values tensors of a map, become elements_ptr
+ *              When view is set, the data of a numeric tensor is not copied and points into the Ort::Value
+ *              The elements of sequences and maps are always copied
  */
-void Predictor::AddOutput(Ort::Value& value) {
+static ORT_Value ConvertValue(Ort::Value& value, bool view = false) {
+  if (static_cast<OrtValue*>(value) == nullptr) {
+    throw Ort::Exception(std::string("The output has already been taken in ConvertValue."), ORT_INVALID_ARGUMENT);
+  }
+
   // base case
   if (value.IsTensor()) {
     auto tensor_info = value.GetTensorTypeAndShapeInfo();
     auto dims = tensor_info.GetShape();
-    int64_t *shapes = (int64_t*) malloc(sizeof(int64_t) * dims.size());
+    // scalars have no dimensions and zero-element tensors have no data, both pointers are then null
+    int64_t *shapes = dims.empty() ? nullptr : (int64_t*) malloc(sizeof(int64_t) * dims.size());
     size_t size = 1;
     for (size_t i = 0; i < dims.size(); i++) {
       size *= dims[i];
       shapes[i] = dims[i];
     }
-    converted_output_.push_back(ORT_Value{
+    size_t *offsets = nullptr;
+    void *data = nullptr;
+    if (tensor_info.GetElementType() == ONNX_TENSOR_ELEMENT_DATA_TYPE_STRING) {
+      data = ConvertStringTensorToPointer(value, size, &offsets);
+    } else if (size == 0) {
+      data = nullptr;
+    } else if (view) {
+      data = value.GetTensorMutableData<void>();
+    } else {
+      data = ConvertTensorToPointer(value, size);
+    }
+    return ORT_Value{
       .otype = tensor_info.GetElementType(),
-      .data_ptr = ConvertTensorToPointer(value, size),
+      .data_ptr = data,
       .shape_ptr = shapes,
-      .shape_len = dims.size()
-    });
-    return;
+      .shape_len = dims.size(),
+      .offsets_ptr = offsets,
+      .vtype = ONNX_TYPE_TENSOR,
+      .elements_ptr = nullptr,
+      .elements_len = 0
+    };
   }
-  
-  // need to be decomposed, it is a map or a sequence, both can be done in the same way
-  size_t length = value.GetCount();
 
-  for (size_t i = 0; i < length; i++) {
-    auto cur_val = value.GetValue(static_cast<int>(i), allocator_);
-    AddOutput(cur_val);
+  auto vtype = value.GetTypeInfo().GetONNXType();
+  if (vtype != ONNX_TYPE_SEQUENCE && vtype != ONNX_TYPE_MAP) {
+    throw Ort::Exception(std::string("unsupported value type detected in ConvertValue."), ORT_NOT_IMPLEMENTED);
   }
+
+  // a map has two elements, its keys and its values
+  Ort::AllocatorWithDefaultOptions allocator;
+  size_t length = value.GetCount();
+  ORT_Value res{
+    .otype = ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED,
+    .data_ptr = nullptr,
+    .shape_ptr = nullptr,
+    .shape_len = 0,
+    .offsets_ptr = nullptr,
+    .vtype = vtype,
+    .elements_ptr = (ORT_Value*) calloc(length > 0 ? length : 1, sizeof(ORT_Value)),
+    .elements_len = 0
+  };
+  try {
+    for (size_t i = 0; i < length; i++) {
+      // the elements are copies, their data can not be viewed once they are released
+      auto cur_val = value.GetValue(static_cast<int>(i), allocator);
+      res.elements_ptr[i] = ConvertValue(cur_val);
+      res.elements_len++;
+    }
+  } catch (...) {
+    FreeValue(res);
+    throw;
+  }
+  return res;
 }
 
-/* Description: The function need to be called before reading outputs from Go */
-void Predictor::ConvertOutput(void) {
+/* Description: The function need to be called before reading outputs from Go, it converts them again on every call */
+void Run::ConvertOutput(void) {
+  ClearConvertedOutput();
   for (size_t i = 0; i < output_.size(); i++) {
-    AddOutput(output_[i]);
+    if (static_cast<OrtValue*>(output_[i]) == nullptr) {
+      throw Ort::Exception(std::string("The outputs have been taken by a view in Run::ConvertOutput."), ORT_INVALID_ARGUMENT);
+    }
+    converted_output_.push_back(ConvertValue(output_[i]));
+    predictor_->converted_memory_.Add(ConvertedBytes(converted_output_.back()));
   }
 }
 
@@ -229,114 +436,305 @@ void Predictor::EndProfiling(void) {
   	profile_filename_ = session_.EndProfiling(allocator_);
 }
 
-void ORT_EndProfiling(ORT_PredictorContext pred) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
+void ORT_EndProfiling(ORT_PredictorContext pred, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
   auto predictor = (Predictor *)pred;
   if (predictor == nullptr) {
-    throw std::runtime_error(std::string("Invalid pointer to the predictor in ORT_PredictorClear."));
+    throw Ort::Exception(std::string("Invalid pointer to the predictor in ORT_EndProfiling."), ORT_INVALID_ARGUMENT);
   }
   predictor->EndProfiling();
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, void());
+  END_HANDLE_ORT_ERRORS((*err), void());
 }
 
 /* Description: The interface for Go to create a new predictor */
-ORT_PredictorContext ORT_NewPredictor(const char *model_file, ORT_DeviceKind device, bool enable_trace, int device_id) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
+ORT_PredictorContext ORT_NewPredictor(const char *model_file, ORT_DeviceKind device, bool enable_trace, int device_id,
+                                      ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
   const auto ctx = new Predictor(model_file, device, enable_trace, device_id);
   return (ORT_PredictorContext) ctx;
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, (ORT_PredictorContext) nullptr);
+  END_HANDLE_ORT_ERRORS((*err), (ORT_PredictorContext) nullptr);
 }
 
-/* Description: The interface for Go to clear the predictor */
-void ORT_PredictorClear(ORT_PredictorContext pred) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
+/* Description: The interface for Go to get the number of inputs declared by the model */
+int ORT_PredictorNumInputs(ORT_PredictorContext pred, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
   auto predictor = (Predictor *)pred;
   if (predictor == nullptr) {
-    throw std::runtime_error(std::string("Invalid pointer to the predictor in ORT_PredictorClear."));
+    throw Ort::Exception(std::string("Invalid pointer to the predictor in ORT_PredictorNumInputs."), ORT_INVALID_ARGUMENT);
   }
-  predictor->Clear();
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, void());
+  return (int) ((predictor -> input_node_).size());
+  END_HANDLE_ORT_ERRORS((*err), 0);
 }
 
-/* Description: The interface for Go to do inference */
-void ORT_PredictorRun(ORT_PredictorContext pred) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
+/* Description: The interface for Go to get the number of outputs of the model */
+int ORT_PredictorNumOutputs(ORT_PredictorContext pred, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
   auto predictor = (Predictor *)pred;
   if (predictor == nullptr) {
-    throw std::runtime_error(std::string("Invalid pointer to the predictor in ORT_PredictorRun."));
+    throw Ort::Exception(std::string("Invalid pointer to the predictor in ORT_PredictorNumOutputs."), ORT_INVALID_ARGUMENT);
   }
-  predictor->Predict();
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, void());
+  return (int) ((predictor -> output_node_).size());
+  END_HANDLE_ORT_ERRORS((*err), 0);
 }
 
-/* Description: The interface for Go to convert outputs before reading outputs */
-void ORT_PredictorConvertOutput(ORT_PredictorContext pred) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
+/* Description: The interface for Go to get the declaration of an input or an output of the model
+ *              The name is owned by the predictor, the shape is allocated here and freed by Go
+ */
+ORT_NodeInfo ORT_PredictorNodeInfo(ORT_PredictorContext pred, bool input, int index, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
   auto predictor = (Predictor *)pred;
   if (predictor == nullptr) {
-    throw std::runtime_error(std::string("Invalid pointer to the predictor in ORT_PredictorConvertOutput."));
+    throw Ort::Exception(std::string("Invalid pointer to the predictor in ORT_PredictorNodeInfo."), ORT_INVALID_ARGUMENT);
   }
-
-  predictor -> ConvertOutput();
-
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, void());
+  auto &nodes = input ? predictor->input_node_ : predictor->output_node_;
+  if (index < 0 || (size_t) index >= nodes.size()) {
+    throw Ort::Exception(std::string("Invalid node index in ORT_PredictorNodeInfo."), ORT_INVALID_ARGUMENT);
+  }
+  auto type_info = input ? predictor->session_.GetInputTypeInfo(index) : predictor->session_.GetOutputTypeInfo(index);
+
+  ORT_NodeInfo res;
+  res.name = nodes[index];
+  res.vtype = type_info.GetONNXType();
+  res.otype = ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED;
+  res.shape_ptr = nullptr;
+  res.shape_len = 0;
+  res.key_otype = ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED;
+  res.value_otype = ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED;
+  if (res.vtype == ONNX_TYPE_MAP) {
+    // the C++ API of onnxruntime 1.7 has no wrapper for the type of maps
+    const OrtMapTypeInfo *map_info = nullptr;
+    Ort::ThrowOnError(Ort::GetApi().CastTypeInfoToMapTypeInfo(type_info, &map_info));
+    Ort::ThrowOnError(Ort::GetApi().GetMapKeyType(map_info, &res.key_otype));
+    OrtTypeInfo *value_info = nullptr;
+    Ort::ThrowOnError(Ort::GetApi().GetMapValueType(map_info, &value_info));
+    Ort::TypeInfo value_type(value_info);
+    if (value_type.GetONNXType() == ONNX_TYPE_TENSOR) {
+      res.value_otype = value_type.GetTensorTypeAndShapeInfo().GetElementType();
+    }
+  }
+  if (res.vtype == ONNX_TYPE_SEQUENCE) {
+    const OrtSequenceTypeInfo *sequence_info = nullptr;
+    Ort::ThrowOnError(Ort::GetApi().CastTypeInfoToSequenceTypeInfo(type_info, &sequence_info));
+    OrtTypeInfo *element_info = nullptr;
+    Ort::ThrowOnError(Ort::GetApi().GetSequenceElementType(sequence_info, &element_info));
+    Ort::TypeInfo element_type(element_info);
+    if (element_type.GetONNXType() == ONNX_TYPE_TENSOR) {
+      res.value_otype = element_type.GetTensorTypeAndShapeInfo().GetElementType();
+    }
+  }
+  if (res.vtype == ONNX_TYPE_TENSOR) {
+    auto tensor_info = type_info.GetTensorTypeAndShapeInfo();
+    res.otype = tensor_info.GetElementType();
+    auto shape = tensor_info.GetShape();
+    if (!shape.empty()) {
+      res.shape_ptr = (int64_t *) malloc(shape.size() * sizeof(int64_t));
+      if (res.shape_ptr == nullptr) {
+        throw Ort::Exception(std::string("Failed to allocate the shape in ORT_PredictorNodeInfo."), ORT_FAIL);
+      }
+      memcpy(res.shape_ptr, shape.data(), shape.size() * sizeof(int64_t));
+      res.shape_len = shape.size();
+    }
+  }
+  return res;
+  END_HANDLE_ORT_ERRORS((*err), ORT_NodeInfo{});
 }
 
-/* Description: The interface for Go to know the number of converted outputs */
-int ORT_PredictorNumOutputs(ORT_PredictorContext pred) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
+/* Description: The interface for Go to create a run holding the inputs and the outputs of one prediction
+ *              The predictor must outlive the run
+ */
+ORT_RunContext ORT_NewRun(ORT_PredictorContext pred, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
   auto predictor = (Predictor *)pred;
   if (predictor == nullptr) {
-    throw std::runtime_error(std::string("Invalid pointer to the predictor in ORT_PredictorNumOutputs."));
+    throw Ort::Exception(std::string("Invalid pointer to the predictor in ORT_NewRun."), ORT_INVALID_ARGUMENT);
   }
-  return (int) ((predictor -> converted_output_).size());
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, 0);
+  return (ORT_RunContext) new Run(predictor);
+  END_HANDLE_ORT_ERRORS((*err), (ORT_RunContext) nullptr);
 }
 
-/* Description: The interface for Go to get the number of converted outputs */
-ORT_Value ORT_PredictorGetOutput(ORT_PredictorContext pred, int index) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
-  auto predictor = (Predictor *)pred;
-  if (predictor == nullptr) {
-    throw std::runtime_error(std::string("Invalid pointer to the predictor in ORT_PredictorGetOutput."));
+/* Description: The interface for Go to append an input to a run, the run takes the ownership of the value */
+void ORT_RunAddInput(ORT_RunContext r, ORT_ValueContext value, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  // the value is deleted here whatever happens
+  std::unique_ptr<Ort::Value> owned((Ort::Value *) value);
+  auto run = (Run *)r;
+  if (run == nullptr || owned == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the run or the value in ORT_RunAddInput."), ORT_INVALID_ARGUMENT);
+  }
+  run->AddInput(std::move(*owned));
+  END_HANDLE_ORT_ERRORS((*err), void());
+}
+
+/* Description: The interface for Go to do the inference of a run */
+void ORT_RunPredict(ORT_RunContext r, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto run = (Run *)r;
+  if (run == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the run in ORT_RunPredict."), ORT_INVALID_ARGUMENT);
+  }
+  run->Predict();
+  END_HANDLE_ORT_ERRORS((*err), void());
+}
+
+/* Description: The interface for Go to stop a run, ORT_RunPredict fails if the run is still going
+ *              It can be called by another thread than the one running the prediction
+ */
+void ORT_RunTerminate(ORT_RunContext r, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto run = (Run *)r;
+  if (run == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the run in ORT_RunTerminate."), ORT_INVALID_ARGUMENT);
+  }
+  run->run_options_.SetTerminate();
+  END_HANDLE_ORT_ERRORS((*err), void());
+}
+
+/* Description: The interface for Go to convert the outputs of a run before reading them */
+void ORT_RunConvertOutput(ORT_RunContext r, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto run = (Run *)r;
+  if (run == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the run in ORT_RunConvertOutput."), ORT_INVALID_ARGUMENT);
   }
+  run->ConvertOutput();
+  END_HANDLE_ORT_ERRORS((*err), void());
+}
+
+/* Description: The interface for Go to get the number of converted outputs of a run */
+int ORT_RunNumOutputs(ORT_RunContext r, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto run = (Run *)r;
+  if (run == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the run in ORT_RunNumOutputs."), ORT_INVALID_ARGUMENT);
+  }
+  return (int) ((run -> converted_output_).size());
+  END_HANDLE_ORT_ERRORS((*err), 0);
+}
+
+/* Description: The interface for Go to get a converted output, the output is owned by the run */
+ORT_Value ORT_RunGetOutput(ORT_RunContext r, int index, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto run = (Run *)r;
+  if (run == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the run in ORT_RunGetOutput."), ORT_INVALID_ARGUMENT);
+  }
+  if (index < 0 || (size_t) index >= run->converted_output_.size()) {
+    throw Ort::Exception(std::string("Invalid output index in ORT_RunGetOutput."), ORT_INVALID_ARGUMENT);
+  }
+  return run->converted_output_[index];
+  END_HANDLE_ORT_ERRORS((*err), ORT_Value{});
+}
 
-  return (predictor -> converted_output_)[index];
+/* Description: The interface for Go to get the number of outputs of a run, whether they are converted or not */
+int ORT_RunNumOutputValues(ORT_RunContext r, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto run = (Run *)r;
+  if (run == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the run in ORT_RunNumOutputValues."), ORT_INVALID_ARGUMENT);
+  }
+  return (int) ((run -> output_).size());
+  END_HANDLE_ORT_ERRORS((*err), 0);
+}
 
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, ORT_Value{});
+/* Description: The interface for Go to take the ownership of an output of a run
+ *              The caller has to delete the value with ORT_DeleteValue
+ */
+ORT_ValueContext ORT_RunTakeOutput(ORT_RunContext r, int index, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto run = (Run *)r;
+  if (run == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the run in ORT_RunTakeOutput."), ORT_INVALID_ARGUMENT);
+  }
+  if (index < 0 || (size_t) index >= run->output_.size()) {
+    throw Ort::Exception(std::string("Invalid output index in ORT_RunTakeOutput."), ORT_INVALID_ARGUMENT);
+  }
+  if (static_cast<OrtValue*>(run->output_[index]) == nullptr) {
+    throw Ort::Exception(std::string("The output has already been taken in ORT_RunTakeOutput."), ORT_INVALID_ARGUMENT);
+  }
+  // the output is not held by the predictor anymore
+  run->predictor_->tensor_memory_.Add(-TensorBytes(run->output_[index]));
+  return (ORT_ValueContext) new Ort::Value(std::move(run->output_[index]));
+  END_HANDLE_ORT_ERRORS((*err), (ORT_ValueContext) nullptr);
+}
+
+/* Description: The interface for Go to delete a run and the outputs it still owns */
+void ORT_DeleteRun(ORT_RunContext r, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  delete (Run *)r;
+  END_HANDLE_ORT_ERRORS((*err), void());
+}
+
+/* Description: The interface for Go to convert a value it owns
+ *              When view is set, the data of a numeric tensor points into the value and lives as long as it
+ */
+ORT_Value ORT_ValueConvert(ORT_ValueContext value, bool view, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  if (value == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the value in ORT_ValueConvert."), ORT_INVALID_ARGUMENT);
+  }
+  return ConvertValue(*(Ort::Value *) value, view);
+  END_HANDLE_ORT_ERRORS((*err), ORT_Value{});
+}
+
+/* Description: The interface for Go to free a value converted by ORT_ValueConvert */
+void ORT_ValueFree(ORT_Value value, bool view, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  FreeValue(value, view);
+  END_HANDLE_ORT_ERRORS((*err), void());
+}
+
+/* Description: The interface for Go to read the memory held by the tensors of the predictor
+ *              onnxruntime 1.7 does not expose the statistics of the arenas of the session
+ */
+ORT_MemoryStats ORT_PredictorMemoryStats(ORT_PredictorContext pred, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto predictor = (Predictor *)pred;
+  if (predictor == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the predictor in ORT_PredictorMemoryStats."), ORT_INVALID_ARGUMENT);
+  }
+  ORT_MemoryStats stats;
+  stats.tensor_bytes = predictor->tensor_memory_.current_.load();
+  stats.peak_tensor_bytes = predictor->tensor_memory_.peak_.load();
+  stats.converted_bytes = predictor->converted_memory_.current_.load();
+  stats.peak_converted_bytes = predictor->converted_memory_.peak_.load();
+  return stats;
+  END_HANDLE_ORT_ERRORS((*err), ORT_MemoryStats{});
 }
 
 /* Description: The interface for Go to delete the dynamic allocated predictor
  *              The destructor for the predictor will be called when deleting the predictor
  */
-void ORT_PredictorDelete(ORT_PredictorContext pred) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
+void ORT_PredictorDelete(ORT_PredictorContext pred, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
   auto predictor = (Predictor *)pred;
   if (predictor == nullptr) {
-    throw std::runtime_error(std::string("Invalid pointer to the predictor in ORT_PredictorDelete."));
+    throw Ort::Exception(std::string("Invalid pointer to the predictor in ORT_PredictorDelete."), ORT_INVALID_ARGUMENT);
   }
 
   if(predictor -> profile_filename_ != "")
   	remove((predictor -> profile_filename_).c_str());
 
   delete predictor;
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, void());
+  END_HANDLE_ORT_ERRORS((*err), void());
 }
 
 /* Description: The interface for Go to read the profile in framework level from onnxruntime */
-char *ORT_ProfilingRead(ORT_PredictorContext pred) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
+char *ORT_ProfilingRead(ORT_PredictorContext pred, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
   auto predictor = (Predictor *)pred;
   if (predictor == nullptr) {
-    throw std::runtime_error(std::string("Invalid pointer to the predictor in ORT_ProfilingRead."));
+    throw Ort::Exception(std::string("Invalid pointer to the predictor in ORT_ProfilingRead."), ORT_INVALID_ARGUMENT);
   }
   
   std::stringstream ss;
   std::ifstream in(predictor -> profile_filename_);
+  if (!in) {
+    throw Ort::Exception("Failed to open the profile " + predictor->profile_filename_ + " in ORT_ProfilingRead.", ORT_NO_SUCHFILE);
+  }
   ss << in.rdbuf();
   return strdup(ss.str().c_str());
 
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, strdup(""));
+  END_HANDLE_ORT_ERRORS((*err), nullptr);
 }
 
 
@@ -356,72 +754,267 @@ static int64_t Getoffset(void) {
 }
 
 /* Description: The interface for Go to get the start time of the profiler */
-int64_t ORT_ProfilingGetStartTime(ORT_PredictorContext pred) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
+int64_t ORT_ProfilingGetStartTime(ORT_PredictorContext pred, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
   auto predictor = (Predictor *)pred;
   if (predictor == nullptr) {
-    throw std::runtime_error(std::string("Invalid pointer to the predictor in ORT_ProfilingGetStartTime."));
+    throw Ort::Exception(std::string("Invalid pointer to the predictor in ORT_ProfilingGetStartTime."), ORT_INVALID_ARGUMENT);
   }
 
   return static_cast<int64_t>(predictor->session_.GetProfilingStartTimeNs()) + Getoffset();
 
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, -1);
+  END_HANDLE_ORT_ERRORS((*err), -1);
 }
 
-/* Description: The interface for Go to add inputs into the predictor */
-void ORT_AddInput(ORT_PredictorContext pred, void *input, int64_t *dimensions,
-                  int n_dim, ONNXTensorElementDataType dtype) {
-  HANDLE_ORT_ERRORS(ORT_GlobalError);
-  auto predictor = (Predictor *)pred;
-  if (predictor == nullptr) {
-    throw std::runtime_error(std::string("Invalid pointer to the predictor in ORT_AddInput."));
+/* Description: The interface for Go to get the id of the calling thread, as onnxruntime writes it in its profile
+ *              Go has to lock its goroutine to the thread for the id to be the one of the following calls
+ */
+int64_t ORT_ThreadID(ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+#if defined(__APPLE__)
+  uint64_t tid = 0;
+  pthread_threadid_np(nullptr, &tid);
+  return static_cast<unsigned int>(tid);
+#else
+  return static_cast<unsigned int>(syscall(SYS_gettid));
+#endif
+  END_HANDLE_ORT_ERRORS((*err), -1);
+}
+
+/* Description: Create a tensor in memory owned by onnxruntime, copying the elements pointed by input when it is not null
+ *              The memory of Go can not be kept by C++ once a call returns, so the tensors never view it
+ */
+static Ort::Value CreateTensorValue(const void *input, int64_t *dimensions, int n_dim, ONNXTensorElementDataType dtype) {
+  std::vector<int64_t> dims;
+  dims.assign(dimensions, dimensions + n_dim);
+  size_t size = 1;
+  for (int i = 0; i < n_dim; i++)
+    size *= dims[i];
+
+  auto element_size = ElementSize(dtype);
+
+  Ort::AllocatorWithDefaultOptions allocator;
+  auto value = Ort::Value::CreateTensor(allocator, dims.data(), dims.size(), dtype);
+  if (input != nullptr && size > 0) {
+    memcpy(value.GetTensorMutableData<void>(), input, size * element_size);
   }
+  return value;
+}
+
+/* Description: Create a string tensor, the strings are copied into memory owned by onnxruntime */
+static Ort::Value CreateStringTensorValue(const char **input, int64_t *dimensions, int n_dim) {
   std::vector<int64_t> dims;
   dims.assign(dimensions, dimensions + n_dim);
   size_t size = 1;
   for (int i = 0; i < n_dim; i++)
     size *= dims[i];
 
-  auto memory_info = Ort::MemoryInfo::CreateCpu(OrtArenaAllocator, OrtMemTypeDefault);
-  switch (dtype) {
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED:
-      throw std::runtime_error(std::string("undefined data type detected in ORT_AddInput."));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<float>(memory_info, static_cast<float*>(input) , size, dims.data(), dims.size()));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT8:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<uint8_t>(memory_info, static_cast<uint8_t*>(input) , size, dims.data(), dims.size()));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_INT8:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<int8_t>(memory_info, static_cast<int8_t*>(input) , size, dims.data(), dims.size()));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT16:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<uint16_t>(memory_info, static_cast<uint16_t*>(input) , size, dims.data(), dims.size()));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_INT16:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<int16_t>(memory_info, static_cast<int16_t*>(input) , size, dims.data(), dims.size()));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_INT32:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<int32_t>(memory_info, static_cast<int32_t*>(input) , size, dims.data(), dims.size()));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_INT64:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<int64_t>(memory_info, static_cast<int64_t*>(input) , size, dims.data(), dims.size()));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_BOOL:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<bool>(memory_info, static_cast<bool*>(input) , size, dims.data(), dims.size()));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_DOUBLE:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<double>(memory_info, static_cast<double*>(input) , size, dims.data(), dims.size()));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT32:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<uint32_t>(memory_info, static_cast<uint32_t*>(input) , size, dims.data(), dims.size()));
-    break;
-    case ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT64:
-      (predictor -> input_).emplace_back(Ort::Value::CreateTensor<uint64_t>(memory_info, static_cast<uint64_t*>(input) , size, dims.data(), dims.size()));
-    break;
-    default: // c++: FLOAT16; onnxruntime: COMPLEX64, COMPLEX128, BFLOAT16; TODO: Implement String method
-      throw std::runtime_error(std::string("unsupported data type detected in ORT_AddInput."));
+  Ort::AllocatorWithDefaultOptions allocator;
+  auto value = Ort::Value::CreateTensor(allocator, dims.data(), dims.size(), ONNX_TENSOR_ELEMENT_DATA_TYPE_STRING);
+  if (size > 0) {
+    value.FillStringTensor(input, size);
+  }
+  return value;
+}
+
+static string ONNXTypeName(ONNXType type) {
+  switch (type) {
+    case ONNX_TYPE_TENSOR:
+      return "tensor";
+    case ONNX_TYPE_SEQUENCE:
+      return "sequence";
+    case ONNX_TYPE_MAP:
+      return "map";
+    default:
+      return "value of type " + std::to_string(static_cast<int>(type));
+  }
+}
+
+/* Description: Append the value to the inputs, after checking that the model expects that kind of value
+ *              The element types are checked by onnxruntime when running the session
+ */
+void Run::AddInput(Ort::Value value) {
+  size_t index = input_.size();
+  if (index >= predictor_->input_node_.size()) {
+    throw Ort::Exception(std::string("Too many input values in Run::AddInput."), ORT_INVALID_ARGUMENT);
+  }
+  auto expected = predictor_->session_.GetInputTypeInfo(index).GetONNXType();
+  auto actual = value.GetTypeInfo().GetONNXType();
+  if (expected != actual) {
+    throw Ort::Exception("Input " + string(predictor_->input_node_[index]) + " expects a " + ONNXTypeName(expected) +
+                         " but got a " + ONNXTypeName(actual) + " in Run::AddInput.", ORT_INVALID_ARGUMENT);
+  }
+  predictor_->tensor_memory_.Add(TensorBytes(value));
+  input_.emplace_back(std::move(value));
+}
+
+/* Description: The interface for Go to create a tensor, used as an input or as an element of sequences and maps
+ *              The elements pointed by input are copied, the caller has to delete the tensor with ORT_DeleteValue
+ */
+ORT_ValueContext ORT_NewTensorValue(const void *input, int64_t *dimensions, int n_dim, ONNXTensorElementDataType dtype,
+                                    ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  return (ORT_ValueContext) new Ort::Value(CreateTensorValue(input, dimensions, n_dim, dtype));
+  END_HANDLE_ORT_ERRORS((*err), (ORT_ValueContext) nullptr);
+}
+
+/* Description: The interface for Go to create a string tensor, used as an input or as an element of sequences and maps */
+ORT_ValueContext ORT_NewStringTensorValue(const char **input, int64_t *dimensions, int n_dim, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  return (ORT_ValueContext) new Ort::Value(CreateStringTensorValue(input, dimensions, n_dim));
+  END_HANDLE_ORT_ERRORS((*err), (ORT_ValueContext) nullptr);
+}
+
+/* Description: The interface for Go to create a sequence, onnxruntime copies the elements into the sequence
+ *              so the caller still has to delete the elements
+ */
+ORT_ValueContext ORT_NewSequenceValue(ORT_ValueContext *elements, int n_elements, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  std::vector<Ort::Value> values;
+  for (int i = 0; i < n_elements; i++) {
+    auto element = (Ort::Value *) elements[i];
+    if (element == nullptr) {
+      throw Ort::Exception(std::string("Invalid pointer to a sequence element in ORT_NewSequenceValue."), ORT_INVALID_ARGUMENT);
+    }
+    // borrow the element without taking its ownership
+    values.emplace_back(static_cast<OrtValue*>(*element));
+  }
+  try {
+    auto sequence = new Ort::Value(Ort::Value::CreateSequence(values));
+    for (auto &value : values) {
+      value.release();
+    }
+    return (ORT_ValueContext) sequence;
+  } catch (...) {
+    for (auto &value : values) {
+      value.release();
+    }
+    throw;
+  }
+  END_HANDLE_ORT_ERRORS((*err), (ORT_ValueContext) nullptr);
+}
+
+/* Description: The interface for Go to create a map from its keys and values tensors
+ *              onnxruntime copies the tensors into the map so the caller still has to delete them
+ */
+ORT_ValueContext ORT_NewMapValue(ORT_ValueContext keys, ORT_ValueContext values, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  if (keys == nullptr || values == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the keys or the values in ORT_NewMapValue."), ORT_INVALID_ARGUMENT);
+  }
+  return (ORT_ValueContext) new Ort::Value(Ort::Value::CreateMap(*(Ort::Value *) keys, *(Ort::Value *) values));
+  END_HANDLE_ORT_ERRORS((*err), (ORT_ValueContext) nullptr);
+}
+
+/* Description: The interface for Go to delete a value created by the ORT_New*Value functions */
+void ORT_DeleteValue(ORT_ValueContext value, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  delete (Ort::Value *) value;
+  END_HANDLE_ORT_ERRORS((*err), void());
+}
+
+/* Description: The structure binding inputs and outputs of a predictor to tensors owned by the binding
+ *              Go copies its tensors into the bound memory before running and out of it afterwards, without any allocation
+ */
+struct Binding {
+  Binding(Predictor *predictor) : predictor_(predictor), binding_(predictor->session_) {}
+  ~Binding();
+  void *Bind(const char *name, int64_t *dimensions, int n_dim, ONNXTensorElementDataType dtype, bool input);
+  Predictor *predictor_;
+  Ort::IoBinding binding_;
+  // the bound tensors, in memory owned by onnxruntime
+  std::map<string, Ort::Value> inputs_;
+  std::map<string, Ort::Value> outputs_;
+};
+
+/* Description: Bind a new tensor to an input or an output and return its data, which lives as long as the binding
+ *              Binding the same name again replaces the tensor
+ */
+void *Binding::Bind(const char *name, int64_t *dimensions, int n_dim, ONNXTensorElementDataType dtype, bool input) {
+  auto &values = input ? inputs_ : outputs_;
+  auto value = CreateTensorValue(nullptr, dimensions, n_dim, dtype);
+  if (input) {
+    binding_.BindInput(name, value);
+  } else {
+    binding_.BindOutput(name, value);
+  }
+  auto data = value.GetTensorMutableData<void>();
+  predictor_->tensor_memory_.Add(TensorBytes(value));
+  auto previous = values.find(name);
+  if (previous != values.end()) {
+    predictor_->tensor_memory_.Add(-TensorBytes(previous->second));
+    values.erase(previous);
+  }
+  values.emplace(name, std::move(value));
+  return data;
+}
+
+Binding::~Binding() {
+  for (auto &entry : inputs_) {
+    predictor_->tensor_memory_.Add(-TensorBytes(entry.second));
+  }
+  for (auto &entry : outputs_) {
+    predictor_->tensor_memory_.Add(-TensorBytes(entry.second));
+  }
+}
+
+/* Description: The interface for Go to create a binding for a predictor, the predictor must outlive the binding */
+ORT_BindingContext ORT_NewBinding(ORT_PredictorContext pred, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto predictor = (Predictor *)pred;
+  if (predictor == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the predictor in ORT_NewBinding."), ORT_INVALID_ARGUMENT);
+  }
+  return (ORT_BindingContext) new Binding(predictor);
+  END_HANDLE_ORT_ERRORS((*err), (ORT_BindingContext) nullptr);
+}
+
+/* Description: The interface for Go to bind an input, Go writes the input into the returned memory before running
+ *              The memory lives until the input is bound again or the binding is deleted
+ */
+void *ORT_BindingBindInput(ORT_BindingContext bind, const char *name, int64_t *dimensions,
+                           int n_dim, ONNXTensorElementDataType dtype, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto binding = (Binding *)bind;
+  if (binding == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the binding in ORT_BindingBindInput."), ORT_INVALID_ARGUMENT);
+  }
+  return binding->Bind(name, dimensions, n_dim, dtype, true);
+  END_HANDLE_ORT_ERRORS((*err), nullptr);
+}
+
+/* Description: The interface for Go to bind an output, onnxruntime writes the output into the returned memory
+ *              The shape and the type have to be the ones of the output produced by the model
+ */
+void *ORT_BindingBindOutput(ORT_BindingContext bind, const char *name, int64_t *dimensions,
+                            int n_dim, ONNXTensorElementDataType dtype, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto binding = (Binding *)bind;
+  if (binding == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the binding in ORT_BindingBindOutput."), ORT_INVALID_ARGUMENT);
+  }
+  return binding->Bind(name, dimensions, n_dim, dtype, false);
+  END_HANDLE_ORT_ERRORS((*err), nullptr);
+}
+
+/* Description: The interface for Go to run the predictor on the bound inputs and outputs */
+void ORT_BindingRun(ORT_BindingContext bind, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto binding = (Binding *)bind;
+  if (binding == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the binding in ORT_BindingRun."), ORT_INVALID_ARGUMENT);
+  }
+  binding->predictor_->session_.Run(Ort::RunOptions{nullptr}, binding->binding_);
+  END_HANDLE_ORT_ERRORS((*err), void());
+}
+
+/* Description: The interface for Go to delete a binding */
+void ORT_DeleteBinding(ORT_BindingContext bind, ORT_Error *err) {
+  HANDLE_ORT_ERRORS((*err));
+  auto binding = (Binding *)bind;
+  if (binding == nullptr) {
+    throw Ort::Exception(std::string("Invalid pointer to the binding in ORT_DeleteBinding."), ORT_INVALID_ARGUMENT);
   }
-  END_HANDLE_ORT_ERRORS(ORT_GlobalError, void());
+  delete binding;
+  END_HANDLE_ORT_ERRORS((*err), void());
 }
@@ -1,68 +1,115 @@
 package onnxruntime
 
-// #include <stdlib.h>
 // #include "cbits/predictor.hpp"
 import "C"
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"runtime"
 	"strings"
-	"time"
-	"unsafe"
+	"sync"
 
-	"github.com/unknwon/com"
 	"github.com/c3sr/dlframework/framework/options"
-	cupti "github.com/c3sr/go-cupti"
 	nvidiasmi "github.com/c3sr/nvidia-smi"
 	"github.com/c3sr/tracer"
-	"github.com/k0kubun/pp/v3"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"gorgonia.org/tensor"
 )
 
+// Predictor runs a model, Predict can be called by several goroutines at once.
+// Reload replaces the model while the predictions keep running.
 type Predictor struct {
-	ctx               C.ORT_PredictorContext
-	options           *options.Options
-	cu                *cupti.CUPTI
-	startingTimeSlice []int64
-	endingTimeSlice   []int64
-	ctxSlice          []context.Context
-	predictSpanSlice  []opentracing.Span
+	// mu guards the session and the bindings
+	mu       sync.Mutex
+	session  *session
+	bindings map[*Binding]struct{}
+	// reloading serializes the calls to Reload
+	reloading sync.Mutex
 }
 
-func New(ctx context.Context, opts ...options.Option) (*Predictor, error) {
-	defer PanicOnError()
+func New(ctx context.Context, opts ...options.Option) (_ *Predictor, err error) {
+	defer recoverError(&err)
 
-	span, _ := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_new")
+	s, err := newSession(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	pred := &Predictor{
+		session: s,
+	}
+
+	runtime.SetFinalizer(pred, func(p *Predictor) {
+		p.Close()
+	})
+
+	return pred, nil
+}
+
+// Reload replaces the model of the predictor, e.g. by a new version of the model, without stopping the predictions.
+// opts are applied over the options of the current model, so options.Graph alone loads another model file.
+// The new model must declare the same inputs and outputs with the same types, its inputs must accept
+// the shapes accepted by the current model and its outputs must have the shapes of the current outputs.
+// The predictions started before the swap finish with the current model, which is deleted once they are done
+// and the results and the bindings created from it are closed.
+func (p *Predictor) Reload(ctx context.Context, opts ...options.Option) (err error) {
+	defer recoverError(&err)
+
+	span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_reload")
 	defer span.Finish()
 
-	options := options.New(opts...)
-	modelFile := string(options.Graph())
-	if !com.IsFile(modelFile) {
-		return nil, errors.Errorf("file %s not found", modelFile)
+	p.reloading.Lock()
+	defer p.reloading.Unlock()
+
+	current, err := p.acquire()
+	if err != nil {
+		return err
 	}
+	defer current.release()
 
-	device := fromDevice(options)
-	if device == UnknownDeviceKind {
-		return nil, errors.New("invalid device")
+	s, err := newSession(ctx, append([]options.Option{options.WithOptions(current.options)}, opts...)...)
+	if err != nil {
+		return errors.Wrap(err, "failed to load the new model")
+	}
+	if err := s.compatible(current); err != nil {
+		s.retire()
+		return errors.Wrap(err, "the new model is not compatible with the current one")
 	}
 
-	cModelFile := C.CString(modelFile)
-	defer C.free(unsafe.Pointer(cModelFile))
+	p.mu.Lock()
+	if p.session != current {
+		p.mu.Unlock()
+		s.retire()
+		return errors.New("predictor is closed")
+	}
+	p.session = s
+	p.mu.Unlock()
+
+	current.retire()
+	return nil
+}
 
-	deviceID := options.Devices()[0].ID()
+// acquire returns the current session of the predictor, the caller must release it once done
+func (p *Predictor) acquire() (*session, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	pred := &Predictor{
-		ctx:     C.ORT_NewPredictor(cModelFile, C.ORT_DeviceKind(device), C.bool(options.TraceLevel() >= tracer.FRAMEWORK_TRACE), C.int(deviceID),),
-		options: options,
+	if p.session == nil {
+		return nil, errors.New("predictor is closed")
 	}
+	p.session.mu.Lock()
+	p.session.users++
+	p.session.mu.Unlock()
+	return p.session, nil
+}
 
-	runtime.SetFinalizer(pred, func(p *Predictor) {
-		p.Close()
-	})
-
-	return pred, GetError()
+// closed tells whether the predictor has been closed
+func (p *Predictor) closed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.session == nil
 }
 
 func fromDevice(opts *options.Options) DeviceKind {
@@ -76,164 +123,212 @@ func fromDevice(opts *options.Options) DeviceKind {
 	return device
 }
 
-func (p *Predictor) addinput(ten *tensor.Dense) {
-	shape := make([]int64, len(ten.Shape()))
-	for i, s := range ten.Shape() {
-		shape[i] = int64(s)
+// Predict runs the model on tensors, the outputs are read from the returned result
+func (p *Predictor) Predict(ctx context.Context, inputs []tensor.Tensor) (*Result, error) {
+	values := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		values[i] = input
 	}
-	var shapePtr *C.int64_t
-	shapePtr = (*C.int64_t)(unsafe.Pointer(&shape[0]))
-
-	C.ORT_AddInput(p.ctx, ten.Pointer(), shapePtr, C.int(len(shape)), fromType(ten))
-
-	runtime.KeepAlive(shape)
+	return p.PredictValues(ctx, values)
 }
 
-func (p *Predictor) Predict(ctx context.Context, inputs []tensor.Tensor) error {
-	defer PanicOnError()
-	if len(inputs) < 1 {
-		return errors.New("input nil or empty")
+// PredictSlices runs the model on inputs held in plain Go slices, without going through gorgonia tensors
+func (p *Predictor) PredictSlices(ctx context.Context, inputs []Value) (*Result, error) {
+	values := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		values[i] = input
 	}
+	return p.PredictValues(ctx, values)
+}
 
-	C.ORT_PredictorClear(p.ctx)
+// PredictValues runs the model, each input is a Value, a tensor.Tensor, a Sequence or a Map
+// and has to match the kind of the corresponding input of the model. The run is stopped when ctx is done.
+func (p *Predictor) PredictValues(ctx context.Context, inputs []interface{}) (_ *Result, err error) {
+	defer recoverError(&err)
 
-	for _, input := range inputs {
-		dense, ok := input.(*tensor.Dense)
-		if !ok {
-			return errors.New("expecting a dense tensor")
-		}
-		p.addinput(dense)
+	res, spanOptions, err := p.prepare(inputs)
+	if err != nil {
+		return nil, err
 	}
 
-	predictSpan, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_predict")
+	if err := res.run(ctx, spanOptions...); err != nil {
+		res.Close()
+		return nil, err
+	}
+	return res, nil
+}
 
-	if tracer.GetLevel() < tracer.FRAMEWORK_TRACE {
-		defer predictSpan.Finish()
+// prepare creates a result holding the inputs copied for onnxruntime, along with the options of its c_predict span
+func (p *Predictor) prepare(inputs []interface{}) (*Result, []opentracing.StartSpanOption, error) {
+	if len(inputs) < 1 {
+		return nil, nil, errors.New("input nil or empty")
 	}
 
-	err := p.cuptiStart(ctx)
+	s, err := p.acquire()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	defer p.cuptiClose()
-
-	if tracer.GetLevel() >= tracer.FRAMEWORK_TRACE {
-		p.predictSpanSlice = append(p.predictSpanSlice, predictSpan)
-		p.ctxSlice = append(p.ctxSlice, ctx)
-		p.startingTimeSlice = append(p.startingTimeSlice, time.Now().UnixNano())
+	// the result uses the session until it is closed
+	res, err := s.newResult()
+	if err != nil {
+		s.release()
+		return nil, nil, err
 	}
 
-	C.ORT_PredictorRun(p.ctx)
+	values := make([]interface{}, len(inputs))
+	converted := []string{}
+	for i, input := range inputs {
+		switch in := input.(type) {
+		case Value, tensor.Tensor:
+			v, err := toValue(in)
+			if err != nil {
+				res.Close()
+				return nil, nil, err
+			}
+			v, conversion, err := s.castInput(i, v)
+			if err != nil {
+				res.Close()
+				return nil, nil, err
+			}
+			if conversion != "" {
+				converted = append(converted, conversion)
+			}
+			values[i] = v
+		case Map:
+			if err := s.checkMapInput(i, in); err != nil {
+				res.Close()
+				return nil, nil, err
+			}
+			values[i] = in
+		case Sequence:
+			if err := s.checkSequenceInput(i, in); err != nil {
+				res.Close()
+				return nil, nil, err
+			}
+			values[i] = in
+		default:
+			res.Close()
+			return nil, nil, errors.Errorf("unsupported input of type %T", input)
+		}
+		if err := res.addInput(values[i]); err != nil {
+			res.Close()
+			return nil, nil, err
+		}
+	}
 
-	if tracer.GetLevel() >= tracer.FRAMEWORK_TRACE {
-		p.endingTimeSlice = append(p.endingTimeSlice, time.Now().UnixNano())
+	spanOptions := []opentracing.StartSpanOption{}
+	if len(converted) > 0 {
+		spanOptions = append(spanOptions, opentracing.Tag{Key: "converted_inputs", Value: strings.Join(converted, ",")})
 	}
 
-	return GetError()
+	return res, spanOptions, nil
 }
 
-func (p *Predictor) ReadPredictionOutput(ctx context.Context) ([]tensor.Tensor, error) {
-	defer PanicOnError()
-
-	span, _ := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_read_predicted_output")
-	defer span.Finish()
-
-	C.ORT_PredictorConvertOutput(p.ctx)
-
-	cNumOutputs := int(C.ORT_PredictorNumOutputs(p.ctx))
-
-	if cNumOutputs == 0 {
-		return nil, errors.New("zero number of tensors")
+// checkMapInput checks that the keys and the values of the i-th input have the types of the map declared by the model,
+// a model expecting another kind of input is reported when the input is added to the run
+func (s *session) checkMapInput(i int, m Map) error {
+	if i >= len(s.inputs) || s.inputs[i].vtype != C.ONNX_TYPE_MAP || m.Keys == nil || m.Values == nil {
+		return nil
 	}
-
-	res := make([]tensor.Tensor, cNumOutputs)
-
-	for i := 0; i < cNumOutputs; i++ {
-		cPredictions := C.ORT_PredictorGetOutput(p.ctx, C.int(i))
-		// The allocated memory will be deleted when destructor of predictor in c++ is called
-		res[i] = ortValueToTensor(cPredictions)
+	node := s.inputs[i]
+	check := func(kind string, got reflect.Type, want C.ONNXTensorElementDataType) error {
+		if want == C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED || fromType(got) == want {
+			return nil
+		}
+		if wantType, ok := toType(want); ok {
+			return newError(ErrInvalidArgument, "input %s expects a map with %s of type %v but got %v", node.name, kind, wantType, got)
+		}
+		return newError(ErrInvalidArgument, "input %s expects a map with %s of onnx type %d but got %v", node.name, kind, int(want), got)
 	}
-
-	if err := GetError(); err != nil {
-		return nil, err
+	if err := check("keys", m.KeyType(), node.keyType); err != nil {
+		return err
 	}
-
-	return res, nil
+	return check("values", m.ValueType(), node.valueType)
 }
 
-func (p *Predictor) Close() {
-	if p == nil {
-		return
+// checkSequenceInput checks that the elements of the i-th input are tensors of the type declared by the model,
+// the elements of the tensors are checked when they are converted
+func (s *session) checkSequenceInput(i int, seq Sequence) error {
+	if i >= len(s.inputs) || s.inputs[i].vtype != C.ONNX_TYPE_SEQUENCE || s.inputs[i].valueType == C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED {
+		return nil
 	}
-
-	if p.ctx != nil && p.options.TraceLevel() >= tracer.FRAMEWORK_TRACE {
-		C.ORT_EndProfiling(p.ctx)
-		start_time := int64(C.ORT_ProfilingGetStartTime(p.ctx))
-
-		profBuffer, err := p.ReadProfile()
-		if err != nil {
-			pp.Println(err)
-			return
+	node := s.inputs[i]
+	for j, element := range seq {
+		var got reflect.Type
+		switch e := element.(type) {
+		case tensor.Tensor:
+			got = e.Dtype().Type
+		case Value:
+			if reflect.TypeOf(e.Data) == nil || reflect.TypeOf(e.Data).Kind() != reflect.Slice {
+				continue
+			}
+			got = reflect.TypeOf(e.Data).Elem()
+		default:
+			return newError(ErrInvalidArgument, "input %s expects a sequence of tensors but element %d is a %T", node.name, j, element)
 		}
-
-		t, err := NewTrace(profBuffer, start_time)
-		if err != nil {
-			panic(err)
-		}
-
-		tSlice, err := SplitTrace(t, p.startingTimeSlice, p.endingTimeSlice)
-		if err != nil {
-			panic(err)
+		if fromType(got) == node.valueType {
+			continue
 		}
-
-		for batchNum, ctx := range p.ctxSlice {
-			tSlice[batchNum].Publish(ctx, tracer.FRAMEWORK_TRACE)
-			p.predictSpanSlice[batchNum].FinishWithOptions(opentracing.FinishOptions{
-				FinishTime: time.Unix(0, p.endingTimeSlice[batchNum]),
-			})
+		if wantType, ok := toType(node.valueType); ok {
+			return newError(ErrInvalidArgument, "input %s expects a sequence of tensors of type %v but element %d has type %v", node.name, wantType, j, got)
 		}
+		return newError(ErrInvalidArgument, "input %s expects a sequence of tensors of onnx type %d but element %d has type %v", node.name, int(node.valueType), j, got)
+	}
+	return nil
+}
 
-		// clear records
-		p.startingTimeSlice = nil
-		p.endingTimeSlice = nil
-		p.ctxSlice = nil
-		p.predictSpanSlice = nil
+// castInput casts the i-th input to the element type declared by the model when AutoCast is enabled,
+// it also returns a description of the conversion, empty when the input is left as it is
+func (s *session) castInput(i int, v Value) (Value, string, error) {
+	if s.castPolicy == nil || i >= len(s.inputs) {
+		return v, "", nil
+	}
+	to, ok := toType(s.inputs[i].dataType)
+	if !ok {
+		return v, "", nil
+	}
+	if err := v.check(); err != nil {
+		return v, "", err
 	}
 
-	if p.ctx != nil {
-		C.ORT_PredictorDelete(p.ctx)
+	from := reflect.TypeOf(v.Data).Elem()
+	if from == to {
+		return v, "", nil
 	}
-	p.ctx = nil
 
+	res, err := castValue(v, to, *s.castPolicy)
+	if err != nil {
+		return v, "", errors.Wrapf(err, "failed to cast input %s from %v to %v", s.inputs[i].name, from, to)
+	}
+	return res, fmt.Sprintf("%s:%v->%v", s.inputs[i].name, from, to), nil
 }
 
-func (p *Predictor) cuptiStart(ctx context.Context) error {
-	if p.options.TraceLevel() < tracer.SYSTEM_LIBRARY_TRACE {
+// Close closes the bindings of the predictor, its model is deleted once the results created from it are closed.
+// The error is the one of publishing the profile of the model or of deleting it, when the model is deleted here.
+func (p *Predictor) Close() (err error) {
+	if p == nil {
 		return nil
 	}
-	metrics := []string{}
-	if p.options.GPUMetrics() != "" {
-		metrics = strings.Split(p.options.GPUMetrics(), ",")
-	}
-
-	cu, err := cupti.New(cupti.Context(ctx),
-		cupti.SamplingPeriod(0),
-		cupti.Metrics(metrics),
-	)
-	if err != nil {
-		return err
+	defer recoverError(&err)
+
+	p.mu.Lock()
+	s := p.session
+	p.session = nil
+	bindings := p.bindings
+	p.bindings = nil
+	p.mu.Unlock()
+
+	for b := range bindings {
+		if closeErr := b.Close(); err == nil {
+			err = closeErr
+		}
 	}
 
-	p.cu = cu
-	return nil
-}
-
-func (p *Predictor) cuptiClose() {
-	if p.cu == nil {
-		return
+	if s != nil {
+		if closeErr := s.retire(); err == nil {
+			err = closeErr
+		}
 	}
-	p.cu.Wait()
-	p.cu.Close()
-	p.cu = nil
+	return err
 }
@@ -22,7 +22,8 @@ var (
 	onnxModelPath = filepath.Join(thisDir, "examples", "_fixtures", "torchvision_alexnet", "torchvision_alexnet.onnx")
 )
 
-func TestOnnxruntimePredictor(t *testing.T) {
+// predictAlexnet runs alexnet on a batch of two images, the first filled with 0 and the second with 1
+func predictAlexnet(t *testing.T) (*Predictor, *Result) {
 
 	var input []float32
 	size := 1
@@ -54,15 +55,13 @@ func TestOnnxruntimePredictor(t *testing.T) {
 	)
 
 	if err != nil {
-		t.Errorf("Onnxruntime predictor initialization failed %v", err)
+		t.Fatalf("Onnxruntime predictor initialization failed %v", err)
 	}
 
-	defer predictor.Close()
-
-	dims := shape
+	dims := append([]int{}, shape...)
 	dims[0] = batchSize
 
-	err = predictor.Predict(ctx, []gotensor.Tensor{
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
 		gotensor.New(
 			gotensor.Of(gotensor.Float32),
 			gotensor.WithBacking(input),
@@ -71,10 +70,19 @@ func TestOnnxruntimePredictor(t *testing.T) {
 	})
 
 	if err != nil {
-		t.Errorf("Onnxruntime predictor predicting failed %v", err)
+		predictor.Close()
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
 	}
 
-	output, err := predictor.ReadPredictionOutput(ctx)
+	return predictor, result
+}
+
+func TestOnnxruntimePredictor(t *testing.T) {
+	predictor, result := predictAlexnet(t)
+	defer predictor.Close()
+	defer result.Close()
+
+	output, err := result.ReadPredictionOutput(context.Background())
 
 	if err != nil {
 		t.Errorf("Onnxruntime predictor read prediction output failed %v", err)
@@ -89,6 +97,29 @@ func TestOnnxruntimePredictor(t *testing.T) {
 	assert.Equal(t, 2000, len(scores))
 }
 
+func TestOnnxruntimePredictorSamples(t *testing.T) {
+	predictor, result := predictAlexnet(t)
+	defer predictor.Close()
+	defer result.Close()
+
+	samples, err := result.ReadPredictionOutputSamples(context.Background())
+
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+
+	assert.Equal(t, batchSize, len(samples))
+
+	scores := samples[0][0].Data().([]float32)
+	assert.InDelta(t, float32(-1.2268), scores[0], 0.0001)
+	assert.InDelta(t, float32(1.4082), scores[999], 0.0001)
+	assert.Equal(t, 1000, len(scores))
+
+	scores = samples[1][0].Data().([]float32)
+	assert.InDelta(t, float32(-0.7274), scores[0], 0.0001)
+	assert.InDelta(t, float32(0.8530), scores[999], 0.0001)
+}
+
 func TestMain(m *testing.M) {
 	config.Init(
 		config.AppName("carml"),
@@ -0,0 +1,189 @@
+package onnxruntime
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/c3sr/dlframework/framework/options"
+	"github.com/pkg/errors"
+)
+
+// NodeProfile is the execution of a node of the model during a run, as recorded by the onnxruntime profiler
+type NodeProfile struct {
+	Name     string
+	OpType   string
+	Provider string
+	Start    time.Time
+	Duration time.Duration
+	ThreadID int64
+	// InputShapes and OutputShapes are empty when onnxruntime does not record them
+	InputShapes  []ProfileShape
+	OutputShapes []ProfileShape
+}
+
+// ProfileShape is the element type and the shape of an input or an output of a node
+type ProfileShape struct {
+	Type string
+	Dims []int64
+}
+
+// RunProfile is a run of the model and the nodes it executed, in the order of the trace
+type RunProfile struct {
+	Start    time.Time
+	Duration time.Duration
+	ThreadID int64
+	Nodes    []NodeProfile
+}
+
+type profilingKey struct{}
+
+// EnableProfiling makes onnxruntime profile the runs of the model, for Predictor.Profile,
+// without having to set a trace level of FRAMEWORK_TRACE
+func EnableProfiling() options.Option {
+	return func(o *options.Options) {
+		ctx := o.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		o.SetContext(context.WithValue(ctx, profilingKey{}, true))
+	}
+}
+
+func profilingEnabled(o *options.Options) bool {
+	ctx := o.Context()
+	if ctx == nil {
+		return false
+	}
+	enabled, _ := ctx.Value(profilingKey{}).(bool)
+	return enabled
+}
+
+// Profile ends the profiling of the current model of the predictor and returns its runs.
+// onnxruntime can not profile a model again once its profile is written, so the runs made afterwards are not
+// profiled, and the following calls return the same runs. The predictor has to be created with EnableProfiling,
+// or with a trace level of at least FRAMEWORK_TRACE in which case the spans published on close hold the same runs.
+func (p *Predictor) Profile() ([]RunProfile, error) {
+	t, _, err := p.endProfile()
+	if err != nil {
+		return nil, err
+	}
+	return ProfileRuns(t)
+}
+
+// OpReport ends the profiling of the current model of the predictor like Profile,
+// and aggregates the times of its kernels
+func (p *Predictor) OpReport(groupBy GroupBy) (*OpReport, error) {
+	t, _, err := p.endProfile()
+	if err != nil {
+		return nil, err
+	}
+	return NewOpReport(t, groupBy)
+}
+
+// endProfile ends the profiling of the current session, returning its profile and the spans recorded until then
+func (p *Predictor) endProfile() (*Trace, []Span, error) {
+	s, err := p.acquire()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer s.release()
+
+	if !s.profiling {
+		return nil, nil, newError(ErrInvalidArgument, "the predictor was not created with profiling enabled")
+	}
+	t, err := s.endProfile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	spans := append([]Span(nil), s.spans...)
+	s.mu.Unlock()
+	return t, spans, nil
+}
+
+// ProfileRuns returns the runs of the model recorded in the trace of an onnxruntime profile.
+// The nodes are matched to the run they happened in, which is the one running on the same thread
+// when several runs overlap.
+func ProfileRuns(t *Trace) ([]RunProfile, error) {
+	var runs []RunProfile
+	for _, event := range t.TraceEvents {
+		if event.Name == "model_run" {
+			runs = append(runs, RunProfile{
+				Start:    event.StartTime,
+				Duration: time.Duration(event.Duration) * time.Microsecond,
+				ThreadID: event.ThreadID,
+			})
+		}
+	}
+
+	for _, event := range t.TraceEvents {
+		if event.Category != "Node" || !strings.HasSuffix(event.Name, "_kernel_time") {
+			continue
+		}
+		node, err := nodeProfile(event)
+		if err != nil {
+			return nil, err
+		}
+
+		run := -1
+		for i := range runs {
+			end := runs[i].Start.Add(runs[i].Duration)
+			if node.Start.Before(runs[i].Start) || node.Start.After(end) {
+				continue
+			}
+			if run == -1 || runs[i].ThreadID == node.ThreadID {
+				run = i
+			}
+			if runs[i].ThreadID == node.ThreadID {
+				break
+			}
+		}
+		// the nodes run when initializing the session belong to no run
+		if run == -1 {
+			continue
+		}
+		runs[run].Nodes = append(runs[run].Nodes, node)
+	}
+	return runs, nil
+}
+
+func nodeProfile(event TraceEvent) (NodeProfile, error) {
+	node := NodeProfile{
+		Name:     strings.TrimSuffix(event.Name, "_kernel_time"),
+		OpType:   event.Arguments["op_name"],
+		Provider: event.Arguments["provider"],
+		Start:    event.StartTime,
+		Duration: time.Duration(event.Duration) * time.Microsecond,
+		ThreadID: event.ThreadID,
+	}
+
+	var err error
+	if node.InputShapes, err = parseProfileShapes(event.Arguments["input_type_shape"]); err != nil {
+		return NodeProfile{}, errors.Wrapf(err, "failed to parse the input shapes of node %s", node.Name)
+	}
+	if node.OutputShapes, err = parseProfileShapes(event.Arguments["output_type_shape"]); err != nil {
+		return NodeProfile{}, errors.Wrapf(err, "failed to parse the output shapes of node %s", node.Name)
+	}
+	return node, nil
+}
+
+// parseProfileShapes parses shapes recorded by onnxruntime as [{"float":[1,3,224,224]},...]
+func parseProfileShapes(data string) ([]ProfileShape, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var records []map[string][]int64
+	if err := json.Unmarshal([]byte(data), &records); err != nil {
+		return nil, err
+	}
+	shapes := make([]ProfileShape, 0, len(records))
+	for _, record := range records {
+		for typ, dims := range record {
+			shapes = append(shapes, ProfileShape{Type: typ, Dims: dims})
+		}
+	}
+	return shapes, nil
+}
@@ -0,0 +1,93 @@
+package onnxruntime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestProfile(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t), EnableProfiling())
+	defer predictor.Close()
+
+	for i := 0; i < 3; i++ {
+		runTestPredictor(t, predictor,
+			gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)))
+	}
+
+	runs, err := predictor.Profile()
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor profile failed %v", err)
+	}
+	if assert.Len(t, runs, 3) {
+		for _, run := range runs {
+			if assert.Len(t, run.Nodes, 1) {
+				assert.Equal(t, "Add", run.Nodes[0].OpType)
+				assert.Equal(t, "CPUExecutionProvider", run.Nodes[0].Provider)
+			}
+		}
+	}
+
+	// the runs after the end of the profiling are not profiled
+	runTestPredictor(t, predictor,
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)))
+	again, err := predictor.Profile()
+	assert.NoError(t, err)
+	assert.Equal(t, runs, again)
+}
+
+func TestProfileDisabled(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	_, err := predictor.Profile()
+	assert.Error(t, err)
+}
+
+func TestProfileRuns(t *testing.T) {
+	trace, err := NewTrace(`[
+		{"cat": "Session", "name": "session_initialization", "ts": 0, "dur": 5, "tid": 1},
+		{"cat": "Node", "name": "init_kernel_time", "ts": 1, "dur": 1, "tid": 1, "args": {"op_name": "Constant"}},
+		{"cat": "Session", "name": "model_run", "ts": 10, "dur": 20, "tid": 1},
+		{"cat": "Session", "name": "model_run", "ts": 12, "dur": 20, "tid": 2},
+		{"cat": "Node", "name": "conv_fence_before", "ts": 11, "dur": 0, "tid": 1, "args": {"op_name": "Conv"}},
+		{"cat": "Node", "name": "conv_kernel_time", "ts": 11, "dur": 4, "tid": 1, "args": {
+			"op_name": "Conv",
+			"provider": "CPUExecutionProvider",
+			"input_type_shape": [{"float": [1, 3, 8, 8]}, {"float": [4, 3, 3, 3]}],
+			"output_type_shape": [{"float": [1, 4, 6, 6]}]
+		}},
+		{"cat": "Node", "name": "conv_kernel_time", "ts": 13, "dur": 4, "tid": 2, "args": {"op_name": "Conv", "provider": "CPUExecutionProvider"}},
+		{"cat": "Node", "name": "relu_kernel_time", "ts": 16, "dur": 2, "tid": 1, "args": {"op_name": "Relu", "provider": "CPUExecutionProvider"}}
+	]`, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the trace %v", err)
+	}
+
+	runs, err := ProfileRuns(trace)
+	assert.NoError(t, err)
+	if !assert.Len(t, runs, 2) {
+		return
+	}
+	assert.Equal(t, 20*time.Microsecond, runs[0].Duration)
+	assert.Equal(t, int64(1), runs[0].ThreadID)
+
+	// the overlapping runs keep the nodes of their own thread
+	if assert.Len(t, runs[0].Nodes, 2) {
+		conv := runs[0].Nodes[0]
+		assert.Equal(t, "conv", conv.Name)
+		assert.Equal(t, "Conv", conv.OpType)
+		assert.Equal(t, "CPUExecutionProvider", conv.Provider)
+		assert.Equal(t, time.Unix(0, 11000), conv.Start)
+		assert.Equal(t, 4*time.Microsecond, conv.Duration)
+		assert.Equal(t, []ProfileShape{{"float", []int64{1, 3, 8, 8}}, {"float", []int64{4, 3, 3, 3}}}, conv.InputShapes)
+		assert.Equal(t, []ProfileShape{{"float", []int64{1, 4, 6, 6}}}, conv.OutputShapes)
+		assert.Equal(t, "Relu", runs[0].Nodes[1].OpType)
+	}
+	if assert.Len(t, runs[1].Nodes, 1) {
+		assert.Equal(t, int64(2), runs[1].Nodes[0].ThreadID)
+		assert.Empty(t, runs[1].Nodes[0].InputShapes)
+	}
+}
@@ -9,8 +9,23 @@ import (
 	"github.com/pkg/errors"
 )
 
+// ReadProfile returns the profile of the current model of the predictor
 func (p *Predictor) ReadProfile() (string, error) {
-	cstr := C.ORT_ProfilingRead(p.ctx)
+	s, err := p.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer s.release()
+
+	return s.readProfile()
+}
+
+func (s *session) readProfile() (string, error) {
+	var cErr C.ORT_Error
+	cstr := C.ORT_ProfilingRead(s.ctx, &cErr)
+	if err := takeError(&cErr); err != nil {
+		return "", errors.Wrap(err, "failed to read the profile")
+	}
 	if cstr == nil {
 		return "", errors.New("failed to read nil profile")
 	}
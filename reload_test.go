@@ -0,0 +1,130 @@
+package onnxruntime
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/c3sr/dlframework/framework/options"
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+// squareModel has the signature of doubleModel
+func squareModel(t testing.TB) string {
+	return writeTestModel(t,
+		[]testNode{{opType: "Mul", inputs: []string{"x", "x"}, outputs: []string{"y"}}},
+		[]testValue{{"x", tensorType(onnxFloat, 4)}},
+		[]testValue{{"y", tensorType(onnxFloat, 4)}},
+	)
+}
+
+func TestReload(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	input := gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4))
+
+	// a result of the old model can still be read after the swap
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{input})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	if err := predictor.Reload(ctx, options.Graph([]byte(squareModel(t)))); err != nil {
+		t.Fatalf("Onnxruntime predictor reload failed %v", err)
+	}
+
+	outputs, err := result.ReadPredictionOutput(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{2, 4, 6, 8}, outputs[0].Data())
+
+	outputs = runTestPredictor(t, predictor, input)
+	assert.Equal(t, []float32{1, 4, 9, 16}, outputs[0].Data())
+}
+
+func TestReloadIncompatible(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	models := []string{
+		// another input size
+		writeTestModel(t,
+			[]testNode{{opType: "Add", inputs: []string{"x", "x"}, outputs: []string{"y"}}},
+			[]testValue{{"x", tensorType(onnxFloat, 3)}},
+			[]testValue{{"y", tensorType(onnxFloat, 3)}},
+		),
+		// another output name
+		writeTestModel(t,
+			[]testNode{{opType: "Add", inputs: []string{"x", "x"}, outputs: []string{"z"}}},
+			[]testValue{{"x", tensorType(onnxFloat, 4)}},
+			[]testValue{{"z", tensorType(onnxFloat, 4)}},
+		),
+		// another element type
+		identityModel(t, onnxDouble),
+	}
+	for _, model := range models {
+		assert.Error(t, predictor.Reload(ctx, options.Graph([]byte(model))))
+	}
+	assert.Error(t, predictor.Reload(ctx, options.Graph([]byte("missing.onnx"))))
+
+	// the predictor keeps its model
+	outputs := runTestPredictor(t, predictor,
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)))
+	assert.Equal(t, []float32{2, 4, 6, 8}, outputs[0].Data())
+}
+
+// TestReloadConcurrentPredict swaps the model back and forth while predicting, every prediction has to be
+// made entirely by one of the models. Run it with -race.
+func TestReloadConcurrentPredict(t *testing.T) {
+	double, square := doubleModel(t), squareModel(t)
+	predictor := newCPUPredictor(t, double)
+	defer predictor.Close()
+
+	ctx := context.Background()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				result, err := predictor.Predict(ctx, []gotensor.Tensor{
+					gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4)),
+				})
+				if err != nil {
+					t.Errorf("Onnxruntime predictor predicting failed %v", err)
+					return
+				}
+				outputs, err := result.ReadPredictionOutput(ctx)
+				result.Close()
+				if err != nil {
+					t.Errorf("Onnxruntime predictor read prediction output failed %v", err)
+					return
+				}
+				data := outputs[0].Data()
+				if !assert.ObjectsAreEqual([]float32{2, 4, 6, 8}, data) && !assert.ObjectsAreEqual([]float32{1, 4, 9, 16}, data) {
+					t.Errorf("unexpected outputs %v", data)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		model := square
+		if i%2 == 1 {
+			model = double
+		}
+		assert.NoError(t, predictor.Reload(ctx, options.Graph([]byte(model))))
+	}
+	close(stop)
+	wg.Wait()
+}
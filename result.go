@@ -0,0 +1,263 @@
+package onnxruntime
+
+// #include "cbits/predictor.hpp"
+import "C"
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/c3sr/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Result holds the inputs and the outputs of one prediction. Every prediction gets its own result,
+// so a predictor can be used by several goroutines at once.
+// The model which ran the prediction is kept until the result is closed, even if the predictor is closed or reloaded.
+type Result struct {
+	session *session
+	ctx     C.ORT_RunContext
+	// onClose is called once the result is closed, the pool uses it to know when a predictor can be closed
+	onClose func()
+}
+
+// newResult creates a result of the session, which the result releases when it is closed
+func (s *session) newResult() (*Result, error) {
+	var cErr C.ORT_Error
+	ctx := C.ORT_NewRun(s.ctx, &cErr)
+	if err := takeError(&cErr); err != nil {
+		return nil, err
+	}
+
+	r := &Result{
+		session: s,
+		ctx:     ctx,
+	}
+	runtime.SetFinalizer(r, (*Result).Close)
+
+	return r, nil
+}
+
+func (r *Result) check() error {
+	if r.ctx == nil {
+		return errors.New("result is closed")
+	}
+	return nil
+}
+
+// run runs the model on the inputs of the result inside the c_predict span
+func (r *Result) run(ctx context.Context, spanOptions ...opentracing.StartSpanOption) error {
+	return r.session.traceRun(ctx, func() error {
+		return r.predict(ctx)
+	}, spanOptions...)
+}
+
+// predict runs the model on the inputs of the result, onnxruntime is told to stop when ctx is done.
+// A failure to stop onnxruntime is returned along with the error of the run.
+func (r *Result) predict(ctx context.Context) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		stopped := make(chan struct{})
+		var terminateErr error
+		go func() {
+			defer close(stopped)
+			select {
+			case <-ctx.Done():
+				var cErr C.ORT_Error
+				C.ORT_RunTerminate(r.ctx, &cErr)
+				terminateErr = takeError(&cErr)
+			case <-stop:
+			}
+		}()
+		// the run must not be terminated once the prediction is over, the result may be closed
+		defer func() {
+			close(stop)
+			<-stopped
+			if terminateErr == nil {
+				return
+			}
+			if err == nil {
+				err = errors.Wrap(terminateErr, "failed to terminate the run")
+			} else {
+				err = errors.Wrapf(err, "failed to terminate the run (%v)", terminateErr)
+			}
+		}()
+	}
+
+	var cErr C.ORT_Error
+	C.ORT_RunPredict(r.ctx, &cErr)
+	if err := takeError(&cErr); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// ReadPredictionOutput returns the outputs of the model as tensors,
+// sequences and maps are flattened into the tensors they contain
+func (r *Result) ReadPredictionOutput(ctx context.Context) ([]tensor.Tensor, error) {
+	values, err := r.ReadPredictionOutputSlices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]tensor.Tensor, len(values))
+	for i, value := range values {
+		res[i] = valueToTensor(value)
+	}
+
+	return res, nil
+}
+
+// ReadPredictionOutputSlices returns the outputs of the model as plain Go slices,
+// sequences and maps are flattened into the tensors they contain
+func (r *Result) ReadPredictionOutputSlices(ctx context.Context) ([]Value, error) {
+	res := []Value{}
+	err := r.readOutputs(ctx, func(output C.ORT_Value) error {
+		values, err := ortValueToValues(output)
+		res = append(res, values...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, errors.New("zero number of tensors")
+	}
+
+	return res, nil
+}
+
+// ReadPredictionOutputSamples returns the outputs of the model split per sample along the batch dimension,
+// using the batch size of the predictor options: the result holds the outputs of the i-th sample at index i.
+// Only the tensor outputs the model declares with a batch dimension are split, the other outputs are
+// shared by all the samples, sequences and maps flattened into the tensors they contain.
+func (r *Result) ReadPredictionOutputSamples(ctx context.Context) ([][]tensor.Tensor, error) {
+	values, err := r.ReadPredictionOutputValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := r.session.options.BatchSize()
+	if batchSize < 1 {
+		return nil, errors.Errorf("invalid batch size %d", batchSize)
+	}
+
+	res := make([][]tensor.Tensor, batchSize)
+	for j, value := range values {
+		t, ok := value.(tensor.Tensor)
+		if !ok {
+			for _, flat := range flattenValue(value) {
+				for i := range res {
+					res[i] = append(res[i], flat)
+				}
+			}
+			continue
+		}
+
+		v, err := tensorToValue(t)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid output %d", j)
+		}
+		batched := r.session.batchedOutput(j) && isBatched(v.Shape, batchSize)
+		samples, err := splitBatchTensor(t, v, batchSize, batched)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to split output %d", j)
+		}
+		for i, sample := range samples {
+			res[i] = append(res[i], sample)
+		}
+	}
+	return res, nil
+}
+
+// ReadPredictionOutputValues returns one value per output of the model, keeping its structure:
+// a tensor.Tensor for tensors, a Sequence for sequences and a Map for maps
+func (r *Result) ReadPredictionOutputValues(ctx context.Context) ([]interface{}, error) {
+	res := []interface{}{}
+	err := r.readOutputs(ctx, func(output C.ORT_Value) error {
+		value, err := ortValueToGo(output)
+		res = append(res, value)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// readOutputs converts the outputs of the prediction and hands them to read in order
+func (r *Result) readOutputs(ctx context.Context, read func(C.ORT_Value) error) (err error) {
+	defer recoverError(&err)
+
+	if err := r.check(); err != nil {
+		return err
+	}
+
+	span, _ := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_read_predicted_output")
+	defer span.Finish()
+	defer r.session.recordSpan("c_read_predicted_output", time.Now())
+
+	// keep the result from being finalized while its outputs are read
+	defer runtime.KeepAlive(r)
+
+	var cErr C.ORT_Error
+	C.ORT_RunConvertOutput(r.ctx, &cErr)
+	if err := takeError(&cErr); err != nil {
+		return err
+	}
+
+	cNumOutputs := int(C.ORT_RunNumOutputs(r.ctx, &cErr))
+	if err := takeError(&cErr); err != nil {
+		return err
+	}
+
+	if cNumOutputs == 0 {
+		return errors.New("zero number of outputs")
+	}
+
+	for i := 0; i < cNumOutputs; i++ {
+		cPredictions := C.ORT_RunGetOutput(r.ctx, C.int(i), &cErr)
+		if err := takeError(&cErr); err != nil {
+			return err
+		}
+		// The allocated memory will be deleted when the result is closed
+		if err := read(cPredictions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close releases the outputs of the prediction, the values read from the result are not affected.
+// Closing the last result of a closed or reloaded predictor deletes its model, the error is the one of Predictor.Close
+// unless deleting the outputs failed.
+func (r *Result) Close() error {
+	if r == nil || r.ctx == nil {
+		return nil
+	}
+	var cErr C.ORT_Error
+	C.ORT_DeleteRun(r.ctx, &cErr)
+	err := takeError(&cErr)
+	r.ctx = nil
+	runtime.SetFinalizer(r, nil)
+	if releaseErr := r.session.release(); err == nil {
+		err = releaseErr
+	}
+
+	if r.onClose != nil {
+		r.onClose()
+	}
+	return err
+}
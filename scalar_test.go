@@ -0,0 +1,107 @@
+package onnxruntime
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+// elementCases holds a single element and an empty slice of every supported element type
+var elementCases = []struct {
+	name     string
+	elemType int
+	one      interface{}
+	empty    interface{}
+}{
+	{"float32", onnxFloat, []float32{1.5}, []float32{}},
+	{"uint8", onnxUint8, []uint8{200}, []uint8{}},
+	{"int8", onnxInt8, []int8{-100}, []int8{}},
+	{"uint16", onnxUint16, []uint16{60000}, []uint16{}},
+	{"int16", onnxInt16, []int16{-30000}, []int16{}},
+	{"int32", onnxInt32, []int32{-2000000000}, []int32{}},
+	{"int64", onnxInt64, []int64{1 << 40}, []int64{}},
+	{"string", onnxString, []string{"threshold"}, []string{}},
+	{"bool", onnxBool, []bool{true}, []bool{}},
+	{"float64", onnxDouble, []float64{0.25}, []float64{}},
+	{"uint32", onnxUint32, []uint32{4000000000}, []uint32{}},
+	{"uint64", onnxUint64, []uint64{1 << 63}, []uint64{}},
+	{"float16", onnxFloat16, []Float16{NewFloat16(0.5)}, []Float16{}},
+	{"bfloat16", onnxBFloat16, []BFloat16{NewBFloat16(-3)}, []BFloat16{}},
+}
+
+// predictSlicesOnce runs the predictor on a single input and returns its single output
+func predictSlicesOnce(t *testing.T, predictor *Predictor, input Value) Value {
+	ctx := context.Background()
+	result, err := predictor.PredictSlices(ctx, []Value{input})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	outputs, err := result.ReadPredictionOutputSlices(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+	assert.Len(t, outputs, 1)
+	return outputs[0]
+}
+
+func TestScalarTensors(t *testing.T) {
+	for _, c := range elementCases {
+		t.Run(c.name, func(t *testing.T) {
+			predictor := newCPUPredictor(t, identityModelAnyShape(t, c.elemType))
+			defer predictor.Close()
+
+			output := predictSlicesOnce(t, predictor, Value{Data: c.one})
+			assert.Empty(t, output.Shape)
+			assert.Equal(t, c.one, output.Data)
+		})
+	}
+}
+
+func TestZeroSizedTensors(t *testing.T) {
+	for _, c := range elementCases {
+		t.Run(c.name, func(t *testing.T) {
+			predictor := newCPUPredictor(t, identityModelAnyShape(t, c.elemType))
+			defer predictor.Close()
+
+			for _, shape := range [][]int64{{0}, {2, 0, 3}} {
+				output := predictSlicesOnce(t, predictor, Value{Data: c.empty, Shape: shape})
+				assert.Equal(t, shape, output.Shape)
+				assert.Equal(t, c.empty, output.Data)
+			}
+		})
+	}
+}
+
+func TestScalarAndZeroSizedGorgoniaTensors(t *testing.T) {
+	ctx := context.Background()
+
+	predictor := newCPUPredictor(t, identityModelAnyShape(t, onnxFloat))
+	defer predictor.Close()
+
+	outputs := runTestPredictor(t, predictor, gotensor.New(gotensor.FromScalar(float32(0.7))))
+	assert.Equal(t, 0, len(outputs[0].Shape()))
+	assert.Equal(t, float32(0.7), outputs[0].Data())
+
+	empty := gotensor.NewDense(gotensor.Float32, gotensor.Shape{0, 4}, gotensor.WithBacking([]float32{}))
+	outputs = runTestPredictor(t, predictor, empty)
+	assert.Equal(t, gotensor.Shape{0, 4}, outputs[0].Shape())
+	assert.Equal(t, 0, reflect.ValueOf(outputs[0].Data()).Len())
+
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{empty})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	view, err := result.ReadPredictionOutputView(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output view failed %v", err)
+	}
+	defer view.Release()
+	assert.Equal(t, gotensor.Shape{0, 4}, view.Tensors()[0].Shape())
+}
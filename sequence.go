@@ -0,0 +1,9 @@
+package onnxruntime
+
+// Sequence is an ONNX sequence value, its elements are tensor.Tensor, Sequence or Map values
+type Sequence []interface{}
+
+// Len returns the number of elements of the sequence
+func (s Sequence) Len() int {
+	return len(s)
+}
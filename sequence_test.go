@@ -0,0 +1,274 @@
+package onnxruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+var zipMapInput = []float32{
+	0.1, 0.7, 0.2,
+	0.5, 0.25, 0.25,
+}
+
+func zipMapModel(t testing.TB, keyType int, labels protoMessage) string {
+	return writeTestModel(t,
+		[]testNode{{opType: "ZipMap", domain: "ai.onnx.ml", inputs: []string{"x"}, outputs: []string{"z"}, attrs: []protoMessage{labels}}},
+		[]testValue{{"x", tensorType(onnxFloat, -1, 3)}},
+		[]testValue{{"z", sequenceType(mapType(keyType, elemTensorType(onnxFloat)))}},
+	)
+}
+
+func TestZipMapInt64Output(t *testing.T) {
+	predictor := newCPUPredictor(t, zipMapModel(t, onnxInt64, int64sAttr("classlabels_int64s", 10, 20, 30)))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(
+			gotensor.Of(gotensor.Float32),
+			gotensor.WithBacking(zipMapInput),
+			gotensor.WithShape(2, 3),
+		),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	values, err := result.ReadPredictionOutputValues(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+
+	assert.Equal(t, 1, len(values))
+	seq, ok := values[0].(Sequence)
+	if !ok {
+		t.Fatalf("expecting a sequence, got %T", values[0])
+	}
+	assert.Equal(t, 2, seq.Len())
+
+	first := seq[0].(Map)
+	assert.Equal(t, 3, first.Len())
+	assert.Equal(t, map[int64]float32{10: 0.1, 20: 0.7, 30: 0.2}, first.GoMap())
+	second := seq[1].(Map)
+	assert.Equal(t, map[int64]float32{10: 0.5, 20: 0.25, 30: 0.25}, second.GoMap())
+}
+
+func TestZipMapStringOutput(t *testing.T) {
+	predictor := newCPUPredictor(t, zipMapModel(t, onnxString, stringsAttr("classlabels_strings", "cat", "dog", "émeu")))
+	defer predictor.Close()
+
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(
+			gotensor.Of(gotensor.Float32),
+			gotensor.WithBacking(zipMapInput),
+			gotensor.WithShape(2, 3),
+		),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	values, err := result.ReadPredictionOutputValues(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+
+	seq := values[0].(Sequence)
+	assert.Equal(t, map[string]float32{"cat": 0.1, "dog": 0.7, "émeu": 0.2}, seq[0].(Map).GoMap())
+	assert.Equal(t, map[string]float32{"cat": 0.5, "dog": 0.25, "émeu": 0.25}, seq[1].(Map).GoMap())
+}
+
+func TestSequenceOfTensorsOutput(t *testing.T) {
+	modelPath := writeTestModel(t,
+		[]testNode{{opType: "SequenceConstruct", inputs: []string{"a", "b"}, outputs: []string{"s"}}},
+		[]testValue{{"a", tensorType(onnxFloat, -1)}, {"b", tensorType(onnxFloat, -1)}},
+		[]testValue{{"s", sequenceType(elemTensorType(onnxFloat))}},
+	)
+	predictor := newCPUPredictor(t, modelPath)
+	defer predictor.Close()
+
+	a := []float32{1, 2, 3}
+	b := []float32{4, 5}
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(a), gotensor.WithShape(len(a))),
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(b), gotensor.WithShape(len(b))),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	values, err := result.ReadPredictionOutputValues(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+
+	seq := values[0].(Sequence)
+	assert.Equal(t, 2, seq.Len())
+	assert.Equal(t, a, seq[0].(gotensor.Tensor).Data().([]float32))
+	assert.Equal(t, b, seq[1].(gotensor.Tensor).Data().([]float32))
+}
+
+func TestMapGoMapSingleEntry(t *testing.T) {
+	m := Map{
+		Keys:   gotensor.New(gotensor.Of(gotensor.Int64), gotensor.WithBacking([]int64{7}), gotensor.WithShape(1)),
+		Values: gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{0.5}), gotensor.WithShape(1)),
+	}
+	assert.Equal(t, 1, m.Len())
+	assert.Equal(t, map[int64]float32{7: 0.5}, m.GoMap())
+}
+
+func TestSequenceInput(t *testing.T) {
+	modelPath := writeTestModel(t,
+		[]testNode{{opType: "ConcatFromSequence", inputs: []string{"s"}, outputs: []string{"y"}, attrs: []protoMessage{intAttr("axis", 0)}}},
+		[]testValue{{"s", sequenceType(elemTensorType(onnxFloat))}},
+		[]testValue{{"y", tensorType(onnxFloat, -1)}},
+	)
+	predictor := newCPUPredictor(t, modelPath)
+	defer predictor.Close()
+
+	ctx := context.Background()
+	result, err := predictor.PredictValues(ctx, []interface{}{
+		Sequence{
+			gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2}), gotensor.WithShape(2)),
+			gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{3, 4, 5}), gotensor.WithShape(3)),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	outputs, err := result.ReadPredictionOutput(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+	assert.Equal(t, []float32{1, 2, 3, 4, 5}, outputs[0].Data().([]float32))
+}
+
+func TestMapInput(t *testing.T) {
+	modelPath := writeTestModel(t,
+		[]testNode{{opType: "DictVectorizer", domain: "ai.onnx.ml", inputs: []string{"m"}, outputs: []string{"y"}, attrs: []protoMessage{stringsAttr("string_vocabulary", "a", "b", "c")}}},
+		[]testValue{{"m", mapType(onnxString, elemTensorType(onnxFloat))}},
+		[]testValue{{"y", tensorType(onnxFloat, -1, 3)}},
+	)
+	predictor := newCPUPredictor(t, modelPath)
+	defer predictor.Close()
+
+	m, err := NewMap(map[string]float32{"c": 3, "a": 1.5})
+	if err != nil {
+		t.Fatalf("failed to build the map input %v", err)
+	}
+
+	ctx := context.Background()
+	result, err := predictor.PredictValues(ctx, []interface{}{m})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	outputs, err := result.ReadPredictionOutput(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+	assert.Equal(t, []float32{1.5, 0, 3}, outputs[0].Data().([]float32))
+}
+
+func TestInputKindMismatch(t *testing.T) {
+	modelPath := writeTestModel(t,
+		[]testNode{{opType: "ConcatFromSequence", inputs: []string{"s"}, outputs: []string{"y"}, attrs: []protoMessage{intAttr("axis", 0)}}},
+		[]testValue{{"s", sequenceType(elemTensorType(onnxFloat))}},
+		[]testValue{{"y", tensorType(onnxFloat, -1)}},
+	)
+	predictor := newCPUPredictor(t, modelPath)
+	defer predictor.Close()
+
+	_, err := predictor.Predict(context.Background(), []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2}), gotensor.WithShape(2)),
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "expects a sequence but got a tensor")
+	}
+}
+
+func TestNewMap(t *testing.T) {
+	m, err := NewMap(map[string]float32{"b": 2, "a": 1, "é": 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "é"}, m.Keys.Data().([]string))
+	assert.Equal(t, []float32{1, 2, 3}, m.Values.Data().([]float32))
+	assert.Equal(t, map[string]float32{"b": 2, "a": 1, "é": 3}, m.GoMap())
+
+	m, err = NewMap(map[int64]float64{3: 0.5, -1: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{-1, 3}, m.Keys.Data().([]int64))
+
+	_, err = NewMap([]int{1})
+	assert.Error(t, err)
+}
+
+func TestNewMapKeyTypes(t *testing.T) {
+	_, err := NewMap(map[int]float32{1: 1})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "int64")
+	}
+	_, err = NewMap(map[int32]float32{1: 1})
+	assert.Error(t, err)
+}
+
+func TestMapInputTypeMismatch(t *testing.T) {
+	modelPath := writeTestModel(t,
+		[]testNode{{opType: "DictVectorizer", domain: "ai.onnx.ml", inputs: []string{"m"}, outputs: []string{"y"}, attrs: []protoMessage{stringsAttr("string_vocabulary", "a", "b", "c")}}},
+		[]testValue{{"m", mapType(onnxString, elemTensorType(onnxFloat))}},
+		[]testValue{{"y", tensorType(onnxFloat, -1, 3)}},
+	)
+	predictor := newCPUPredictor(t, modelPath)
+	defer predictor.Close()
+
+	ctx := context.Background()
+	for _, goMap := range []interface{}{
+		map[int64]float32{1: 1},
+		map[string]float64{"a": 1},
+	} {
+		m, err := NewMap(goMap)
+		if err != nil {
+			t.Fatalf("failed to build the map input %v", err)
+		}
+		_, err = predictor.PredictValues(ctx, []interface{}{m})
+		if assert.Error(t, err) {
+			assert.True(t, errors.Is(err, ErrInvalidArgument), "unexpected error %v", err)
+		}
+	}
+}
+
+func TestSequenceInputTypeMismatch(t *testing.T) {
+	modelPath := writeTestModel(t,
+		[]testNode{{opType: "ConcatFromSequence", inputs: []string{"s"}, outputs: []string{"y"}, attrs: []protoMessage{intAttr("axis", 0)}}},
+		[]testValue{{"s", sequenceType(elemTensorType(onnxFloat))}},
+		[]testValue{{"y", tensorType(onnxFloat, -1)}},
+	)
+	predictor := newCPUPredictor(t, modelPath)
+	defer predictor.Close()
+
+	ctx := context.Background()
+	for _, seq := range []Sequence{
+		{
+			gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2}), gotensor.WithShape(2)),
+			gotensor.New(gotensor.Of(gotensor.Float64), gotensor.WithBacking([]float64{3, 4}), gotensor.WithShape(2)),
+		},
+		{Value{Data: []int64{1, 2}, Shape: []int64{2}}},
+	} {
+		_, err := predictor.PredictValues(ctx, []interface{}{seq})
+		if assert.Error(t, err) {
+			assert.True(t, errors.Is(err, ErrInvalidArgument), "unexpected error %v", err)
+			assert.Contains(t, err.Error(), "expects a sequence of tensors of type float32")
+		}
+	}
+}
@@ -0,0 +1,437 @@
+package onnxruntime
+
+// #include <stdlib.h>
+// #include "cbits/predictor.hpp"
+import "C"
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/c3sr/dlframework/framework/options"
+	cupti "github.com/c3sr/go-cupti"
+	"github.com/c3sr/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/unknwon/com"
+)
+
+// session is a model loaded by onnxruntime with the options it was created from. Reload replaces the session
+// of a predictor, the replaced session is deleted once the predictions, the results and the bindings using it are done.
+type session struct {
+	ctx     C.ORT_PredictorContext
+	options *options.Options
+	// castPolicy is set by the AutoCast option
+	castPolicy *CastPolicy
+	inputs     []nodeInfo
+	outputs    []nodeInfo
+	// profiling is set when onnxruntime profiles the runs of the session
+	profiling bool
+	// modelModTime and modelSize are the state of the model file when it was loaded
+	modelModTime time.Time
+	modelSize    int64
+
+	// mu guards the users, the trace records and the profile, which are shared by the concurrent predictions
+	mu sync.Mutex
+	// users counts the running predictions, the open results and the bindings of the session
+	users int
+	// retired is set once the session is not the one of its predictor anymore
+	retired bool
+	// runs are the predictions recorded to publish the profile
+	runs []tracedRun
+	// spans are the Go side spans of the session while it is profiled
+	spans []Span
+	// the profile is parsed once, when the profiling ends
+	profileEnded bool
+	profile      *Trace
+	profileErr   error
+}
+
+// tracedRun is a prediction of the session, its profile is published under its c_predict span
+type tracedRun struct {
+	ctx  context.Context
+	span opentracing.Span
+	run  TraceRun
+}
+
+// nodeInfo is the declaration of an input or an output of the model,
+// the element type and the shape are only set for tensors, the shape holds -1 for the dimensions of unknown size,
+// the key type is only set for maps, the value type for maps and for the elements of sequences of tensors
+type nodeInfo struct {
+	name      string
+	vtype     C.enum_ONNXType
+	dataType  C.ONNXTensorElementDataType
+	shape     []int64
+	keyType   C.ONNXTensorElementDataType
+	valueType C.ONNXTensorElementDataType
+}
+
+func newSession(ctx context.Context, opts ...options.Option) (*session, error) {
+	span, _ := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_new")
+	defer span.Finish()
+	start := time.Now()
+
+	options := options.New(opts...)
+	modelFile := string(options.Graph())
+	if !com.IsFile(modelFile) {
+		return nil, newError(ErrNoSuchFile, "file %s not found", modelFile)
+	}
+	// the file is checked before loading it, a change while it loads is seen as a change by Watch
+	info, err := os.Stat(modelFile)
+	if err != nil {
+		return nil, newError(ErrNoSuchFile, "file %s not found", modelFile)
+	}
+
+	device := fromDevice(options)
+	if device == UnknownDeviceKind {
+		return nil, newError(ErrInvalidArgument, "invalid device")
+	}
+
+	cModelFile := C.CString(modelFile)
+	defer C.free(unsafe.Pointer(cModelFile))
+
+	deviceID := options.Devices()[0].ID()
+
+	profiling := options.TraceLevel() >= tracer.FRAMEWORK_TRACE || profilingEnabled(options)
+
+	var cErr C.ORT_Error
+	cPredictor := C.ORT_NewPredictor(cModelFile, C.ORT_DeviceKind(device), C.bool(profiling), C.int(deviceID), &cErr)
+	if err := takeError(&cErr); err != nil {
+		return nil, err
+	}
+
+	s := &session{
+		ctx:       cPredictor,
+		options:   options,
+		profiling: profiling,
+
+		modelModTime: info.ModTime(),
+		modelSize:    info.Size(),
+	}
+
+	if policy, ok := autoCastPolicy(options); ok {
+		s.castPolicy = &policy
+	}
+
+	if err := s.readSignature(); err != nil {
+		s.retire()
+		return nil, err
+	}
+
+	s.recordSpan("c_new", start)
+	return s, nil
+}
+
+// readSignature records the declarations of the inputs and the outputs of the model
+func (s *session) readSignature() error {
+	var cErr C.ORT_Error
+	numInputs := int(C.ORT_PredictorNumInputs(s.ctx, &cErr))
+	if err := takeError(&cErr); err != nil {
+		return err
+	}
+	numOutputs := int(C.ORT_PredictorNumOutputs(s.ctx, &cErr))
+	if err := takeError(&cErr); err != nil {
+		return err
+	}
+
+	read := func(input bool, n int) ([]nodeInfo, error) {
+		res := make([]nodeInfo, n)
+		for i := range res {
+			info := C.ORT_PredictorNodeInfo(s.ctx, C.bool(input), C.int(i), &cErr)
+			if err := takeError(&cErr); err != nil {
+				return nil, err
+			}
+			shape := make([]int64, int(info.shape_len))
+			if len(shape) > 0 {
+				copy(shape, (*[1 << 30]int64)(unsafe.Pointer(info.shape_ptr))[:len(shape):len(shape)])
+			}
+			C.free(unsafe.Pointer(info.shape_ptr))
+			res[i] = nodeInfo{
+				name:      C.GoString(info.name),
+				vtype:     info.vtype,
+				dataType:  info.otype,
+				shape:     shape,
+				keyType:   info.key_otype,
+				valueType: info.value_otype,
+			}
+		}
+		return res, nil
+	}
+
+	var err error
+	if s.inputs, err = read(true, numInputs); err != nil {
+		return err
+	}
+	if s.outputs, err = read(false, numOutputs); err != nil {
+		return err
+	}
+	return nil
+}
+
+// compatible checks that the model of s can replace the model of old: it has the same inputs and outputs,
+// accepts all the inputs accepted by old and produces outputs old could produce
+func (s *session) compatible(old *session) error {
+	check := func(kind string, nodes, oldNodes []nodeInfo, input bool) error {
+		if len(nodes) != len(oldNodes) {
+			return errors.Errorf("the model has %d %ss instead of %d", len(nodes), kind, len(oldNodes))
+		}
+		for i, node := range nodes {
+			oldNode := oldNodes[i]
+			if node.name != oldNode.name {
+				return errors.Errorf("%s %d is called %s instead of %s", kind, i, node.name, oldNode.name)
+			}
+			if node.vtype != oldNode.vtype || node.dataType != oldNode.dataType ||
+				node.keyType != oldNode.keyType || node.valueType != oldNode.valueType {
+				return errors.Errorf("%s %s changed type", kind, node.name)
+			}
+			if len(node.shape) != len(oldNode.shape) {
+				return errors.Errorf("%s %s has shape %v instead of %v", kind, node.name, node.shape, oldNode.shape)
+			}
+			for j, dim := range node.shape {
+				// an input may accept any size where it accepted one, an output may not
+				wide, narrow := dim, oldNode.shape[j]
+				if !input {
+					wide, narrow = narrow, wide
+				}
+				if wide != narrow && wide != -1 {
+					return errors.Errorf("%s %s has shape %v instead of %v", kind, node.name, node.shape, oldNode.shape)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := check("input", s.inputs, old.inputs, true); err != nil {
+		return err
+	}
+	return check("output", s.outputs, old.outputs, false)
+}
+
+// batchedOutput tells whether the model declares a batch dimension for the j-th output:
+// a symbolic first dimension, or a fixed one the same as the first dimension of the first input
+func (s *session) batchedOutput(j int) bool {
+	if j >= len(s.outputs) || s.outputs[j].vtype != C.ONNX_TYPE_TENSOR || len(s.outputs[j].shape) == 0 {
+		return false
+	}
+	dim := s.outputs[j].shape[0]
+	if dim == -1 {
+		return true
+	}
+	return len(s.inputs) > 0 && len(s.inputs[0].shape) > 0 && s.inputs[0].shape[0] == dim
+}
+
+// release is called when a user of the session is done with it,
+// it returns the error of closing the session when it was its last user
+func (s *session) release() error {
+	s.mu.Lock()
+	s.users--
+	done := s.retired && s.users == 0
+	s.mu.Unlock()
+
+	if done {
+		return s.close()
+	}
+	return nil
+}
+
+// retire marks the session as replaced or closed, it is deleted as soon as it has no users
+func (s *session) retire() error {
+	s.mu.Lock()
+	s.retired = true
+	done := s.users == 0
+	s.mu.Unlock()
+
+	if done {
+		return s.close()
+	}
+	return nil
+}
+
+// traceRun calls run inside the c_predict span, recording what is needed to publish the profile on close
+func (s *session) traceRun(ctx context.Context, run func() error, spanOptions ...opentracing.StartSpanOption) error {
+	predictSpan, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_predict", spanOptions...)
+	defer s.recordSpan("c_predict", time.Now())
+
+	if tracer.GetLevel() < tracer.FRAMEWORK_TRACE {
+		defer predictSpan.Finish()
+	}
+
+	cu, err := s.cuptiStart(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer cuptiClose(cu)
+
+	record := tracer.GetLevel() >= tracer.FRAMEWORK_TRACE
+	var threadID int64
+	if record {
+		// the events of concurrent predictions are told apart by the thread which ran them,
+		// so the prediction has to stay on the thread whose id is recorded
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		var cErr C.ORT_Error
+		threadID = int64(C.ORT_ThreadID(&cErr))
+		if err := takeError(&cErr); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now().UnixNano()
+
+	err = run()
+
+	if tracer.GetLevel() >= tracer.MODEL_TRACE {
+		s.setMemoryTags(predictSpan)
+	}
+
+	if record {
+		s.mu.Lock()
+		s.runs = append(s.runs, tracedRun{
+			ctx:  ctx,
+			span: predictSpan,
+			run:  TraceRun{Start: start, End: time.Now().UnixNano(), ThreadID: threadID},
+		})
+		s.mu.Unlock()
+	}
+
+	return err
+}
+
+// recordSpan records a span of the session ending now, for the Chrome trace. The spans are only recorded while
+// the session is profiled, so that they match the onnxruntime profile.
+func (s *session) recordSpan(name string, start time.Time) {
+	if !s.profiling {
+		return
+	}
+	end := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.profileEnded {
+		s.spans = append(s.spans, Span{Name: name, Start: start, End: end})
+	}
+}
+
+// close publishes the profile of the session and deletes it, the session is deleted even if publishing fails
+func (s *session) close() (err error) {
+	defer func() {
+		var cErr C.ORT_Error
+		C.ORT_PredictorDelete(s.ctx, &cErr)
+		if deleteErr := takeError(&cErr); err == nil {
+			err = deleteErr
+		}
+		s.ctx = nil
+	}()
+	defer recoverError(&err)
+
+	if s.options.TraceLevel() >= tracer.FRAMEWORK_TRACE {
+		return s.publishProfile()
+	}
+	if s.profiling {
+		// the profile file is only known, and removed with the session, once the profiling has ended
+		_, err := s.endProfile()
+		return err
+	}
+	return nil
+}
+
+// endProfile ends the profiling of the session and parses its profile, the first call only does it
+func (s *session) endProfile() (*Trace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.profileEnded {
+		s.profileEnded = true
+		s.profile, s.profileErr = s.readEndedProfile()
+	}
+	return s.profile, s.profileErr
+}
+
+func (s *session) readEndedProfile() (*Trace, error) {
+	var cErr C.ORT_Error
+	C.ORT_EndProfiling(s.ctx, &cErr)
+	if err := takeError(&cErr); err != nil {
+		return nil, errors.Wrap(err, "failed to end profiling")
+	}
+	start_time := int64(C.ORT_ProfilingGetStartTime(s.ctx, &cErr))
+	if err := takeError(&cErr); err != nil {
+		return nil, errors.Wrap(err, "failed to get the start time of the profile")
+	}
+
+	profBuffer, err := s.readProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := NewTrace(profBuffer, start_time)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the profile")
+	}
+	return t, nil
+}
+
+// publishProfile publishes the profile of each prediction as children of its c_predict span
+func (s *session) publishProfile() error {
+	// the spans are finished whatever happens to the profile
+	defer func() {
+		for _, r := range s.runs {
+			r.span.FinishWithOptions(opentracing.FinishOptions{
+				FinishTime: time.Unix(0, r.run.End),
+			})
+		}
+
+		// clear records
+		s.runs = nil
+	}()
+
+	t, err := s.endProfile()
+	if err != nil {
+		return err
+	}
+
+	runs := make([]TraceRun, len(s.runs))
+	for i, r := range s.runs {
+		runs[i] = r.run
+	}
+	tSlice := SplitTraceRuns(t, runs)
+
+	for i, r := range s.runs {
+		if err := tSlice[i].Publish(r.ctx, tracer.FRAMEWORK_TRACE); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *session) cuptiStart(ctx context.Context) (*cupti.CUPTI, error) {
+	if s.options.TraceLevel() < tracer.SYSTEM_LIBRARY_TRACE {
+		return nil, nil
+	}
+	metrics := []string{}
+	if s.options.GPUMetrics() != "" {
+		metrics = strings.Split(s.options.GPUMetrics(), ",")
+	}
+
+	cu, err := cupti.New(cupti.Context(ctx),
+		cupti.SamplingPeriod(0),
+		cupti.Metrics(metrics),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cu, nil
+}
+
+func cuptiClose(cu *cupti.CUPTI) {
+	if cu == nil {
+		return
+	}
+	cu.Wait()
+	cu.Close()
+}
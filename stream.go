@@ -0,0 +1,125 @@
+package onnxruntime
+
+import (
+	"context"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"gorgonia.org/tensor"
+)
+
+// StreamOutput is the outcome of the input at Index in the stream given to PredictStream
+type StreamOutput struct {
+	Index   int
+	Outputs []tensor.Tensor
+	Err     error
+}
+
+// streamItem is an input of the stream on its way through the stages of the pipeline
+type streamItem struct {
+	index       int
+	result      *Result
+	spanOptions []opentracing.StartSpanOption
+	err         error
+}
+
+// PredictStream runs the model on every input received from inputs and sends the outputs in the same order.
+// The inputs are copied for onnxruntime, run and their outputs converted by three stages working at once,
+// depth sets how many inputs may wait between two stages.
+// The output channel is closed once inputs is closed and all its outputs are sent, or when ctx is done.
+// The first error is sent like an output and stops the pipeline, the following inputs are not read.
+// Cancel ctx to stop reading the outputs early.
+func (p *Predictor) PredictStream(ctx context.Context, inputs <-chan []tensor.Tensor, depth int) <-chan StreamOutput {
+	if depth < 1 {
+		depth = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	prepared := make(chan streamItem, depth)
+	predicted := make(chan streamItem, depth)
+	outputs := make(chan StreamOutput, depth)
+
+	go func() {
+		defer close(prepared)
+		for i := 0; ; i++ {
+			var input []tensor.Tensor
+			var ok bool
+			select {
+			case input, ok = <-inputs:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			values := make([]interface{}, len(input))
+			for j, in := range input {
+				values[j] = in
+			}
+			item := streamItem{index: i}
+			func() {
+				defer recoverError(&item.err)
+				item.result, item.spanOptions, item.err = p.prepare(values)
+			}()
+			if !sendStreamItem(ctx, prepared, item) || item.err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer close(predicted)
+		for item := range prepared {
+			if item.err == nil {
+				err := func() (err error) {
+					defer recoverError(&err)
+					return item.result.run(ctx, item.spanOptions...)
+				}()
+				if err != nil {
+					item.result.Close()
+					item.result, item.err = nil, err
+				}
+			}
+			sendStreamItem(ctx, predicted, item)
+		}
+	}()
+
+	go func() {
+		defer close(outputs)
+		defer cancel()
+		for item := range predicted {
+			// the pipeline is stopped, the items still on their way are dropped
+			if ctx.Err() != nil {
+				item.result.Close()
+				continue
+			}
+
+			output := StreamOutput{Index: item.index, Err: item.err}
+			if item.err == nil {
+				output.Outputs, output.Err = item.result.ReadPredictionOutput(ctx)
+				item.result.Close()
+			}
+
+			select {
+			case outputs <- output:
+			case <-ctx.Done():
+			}
+			if output.Err != nil {
+				cancel()
+			}
+		}
+	}()
+
+	return outputs
+}
+
+// sendStreamItem sends item to the next stage, unless the pipeline is stopped in which case its result is closed
+func sendStreamItem(ctx context.Context, next chan<- streamItem, item streamItem) bool {
+	select {
+	case next <- item:
+		return true
+	case <-ctx.Done():
+		item.result.Close()
+		return false
+	}
+}
@@ -0,0 +1,77 @@
+package onnxruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+// streamInputs returns a closed channel holding one input of the double model per size
+func streamInputs(sizes ...int) <-chan []gotensor.Tensor {
+	inputs := make(chan []gotensor.Tensor, len(sizes))
+	for i, size := range sizes {
+		data := make([]float32, size)
+		for j := range data {
+			data[j] = float32(i)
+		}
+		inputs <- []gotensor.Tensor{gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(data), gotensor.WithShape(size))}
+	}
+	close(inputs)
+	return inputs
+}
+
+func TestPredictStream(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	sizes := make([]int, 50)
+	for i := range sizes {
+		sizes[i] = 4
+	}
+
+	i := 0
+	for output := range predictor.PredictStream(context.Background(), streamInputs(sizes...), 4) {
+		if !assert.NoError(t, output.Err) {
+			continue
+		}
+		x := float32(2 * i)
+		assert.Equal(t, i, output.Index)
+		assert.Equal(t, []float32{x, x, x, x}, output.Outputs[0].Data())
+		i++
+	}
+	assert.Equal(t, len(sizes), i)
+}
+
+func TestPredictStreamError(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	// the model only accepts 4 elements, the stream stops at the third input
+	outputs := []StreamOutput{}
+	for output := range predictor.PredictStream(context.Background(), streamInputs(4, 4, 3, 4, 4), 2) {
+		outputs = append(outputs, output)
+	}
+
+	if assert.Len(t, outputs, 3) {
+		assert.NoError(t, outputs[0].Err)
+		assert.NoError(t, outputs[1].Err)
+		assert.Equal(t, 2, outputs[2].Index)
+		assert.Error(t, outputs[2].Err)
+	}
+}
+
+func TestPredictStreamCanceled(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+	defer predictor.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// nothing is ever sent, canceling has to close the outputs
+	inputs := make(chan []gotensor.Tensor)
+	outputs := predictor.PredictStream(ctx, inputs, 1)
+	cancel()
+	for range outputs {
+		t.Errorf("Onnxruntime predictor stream sent an output without inputs")
+	}
+}
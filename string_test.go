@@ -0,0 +1,50 @@
+package onnxruntime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestStringIdentity(t *testing.T) {
+	data := []string{"", "hello", "", "héllo wörld", "日本語のテキスト", "🙂 emoji"}
+
+	predictor := newCPUPredictor(t, identityModel(t, onnxString))
+	defer predictor.Close()
+
+	outputs := runTestPredictor(t, predictor, gotensor.New(
+		gotensor.Of(gotensor.String),
+		gotensor.WithBacking(data),
+		gotensor.WithShape(2, 3),
+	))
+	assert.Equal(t, gotensor.String, outputs[0].Dtype())
+	assert.Equal(t, gotensor.Shape{2, 3}, outputs[0].Shape())
+	assert.Equal(t, data, outputs[0].Data().([]string))
+}
+
+func TestStringAllEmpty(t *testing.T) {
+	data := []string{"", "", ""}
+
+	predictor := newCPUPredictor(t, identityModel(t, onnxString))
+	defer predictor.Close()
+
+	outputs := runTestPredictor(t, predictor, gotensor.New(
+		gotensor.Of(gotensor.String),
+		gotensor.WithBacking(data),
+		gotensor.WithShape(len(data)),
+	))
+	assert.Equal(t, data, outputs[0].Data().([]string))
+}
+
+func TestStringCast(t *testing.T) {
+	predictor := newCPUPredictor(t, castModel(t, onnxString, onnxFloat))
+	defer predictor.Close()
+
+	outputs := runTestPredictor(t, predictor, gotensor.New(
+		gotensor.Of(gotensor.String),
+		gotensor.WithBacking([]string{"1.5", "-2", "0"}),
+		gotensor.WithShape(3),
+	))
+	assert.Equal(t, []float32{1.5, -2, 0}, outputs[0].Data().([]float32))
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/c3sr/tracer"
 	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
 )
 
 type TraceEvent struct {
@@ -26,6 +27,33 @@ type TraceEvent struct {
 	EndTime   time.Time         `json:"-"`
 }
 
+// UnmarshalJSON keeps the arguments which are not strings, such as the shapes of the inputs and the outputs
+// of a node, as their JSON text
+func (t *TraceEvent) UnmarshalJSON(data []byte) error {
+	// traceEvent has the fields of TraceEvent without its methods, so that decoding it does not recurse
+	type traceEvent TraceEvent
+	var raw struct {
+		traceEvent
+		Arguments map[string]json.RawMessage `json:"args,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*t = TraceEvent(raw.traceEvent)
+	if raw.Arguments == nil {
+		return nil
+	}
+	t.Arguments = make(map[string]string, len(raw.Arguments))
+	for key, value := range raw.Arguments {
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			s = string(value)
+		}
+		t.Arguments[key] = s
+	}
+	return nil
+}
+
 func (t TraceEvent) ID() string {
 	return fmt.Sprintf("%s/%v", t.Name, t.ThreadID)
 }
@@ -45,28 +73,105 @@ func (t Trace) Len() int           { return t.TraceEvents.Len() }
 func (t Trace) Swap(i, j int)      { t.TraceEvents.Swap(i, j) }
 func (t Trace) Less(i, j int) bool { return t.TraceEvents.Less(i, j) }
 
+// SplitTrace splits the trace into the predictions which ran between the given starting and ending times,
+// the events after the last prediction are dropped
 func SplitTrace(t *Trace, startSlice []int64, endSlice []int64) ([]*Trace, error) {
+	if len(startSlice) != len(endSlice) {
+		return nil, errors.Errorf("%d starting times for %d ending times", len(startSlice), len(endSlice))
+	}
+	tSlice := make([]*Trace, len(startSlice))
+	for i := range tSlice {
+		tSlice[i] = &Trace{StartTime: time.Unix(0, startSlice[i])}
+	}
+	if len(tSlice) == 0 {
+		return tSlice, nil
+	}
+
 	batchNum := 0
-	tSlice := []*Trace{}
-	tmpTrace := new(Trace)
-	tSlice = append(tSlice, tmpTrace)
-	tmpTrace.StartTime = time.Unix(0, startSlice[batchNum])
 	for _, event := range t.TraceEvents {
 		if event.End < endSlice[batchNum] {
 			if event.Start > startSlice[batchNum] {
-				tmpTrace.TraceEvents = append(tmpTrace.TraceEvents, event)
+				tSlice[batchNum].TraceEvents = append(tSlice[batchNum].TraceEvents, event)
 			}
-		} else {
-			batchNum++
-			tmpTrace = new(Trace)
-			tSlice = append(tSlice, tmpTrace)
-			tmpTrace.StartTime = time.Unix(0, startSlice[batchNum])
-			tmpTrace.TraceEvents = append(tmpTrace.TraceEvents, event)
+			continue
 		}
+		if batchNum+1 == len(tSlice) {
+			break
+		}
+		batchNum++
+		tSlice[batchNum].TraceEvents = append(tSlice[batchNum].TraceEvents, event)
 	}
 	return tSlice, nil
 }
 
+// TraceRun is the time window of a prediction, in nanoseconds, and the id of the thread which ran it
+type TraceRun struct {
+	Start    int64
+	End      int64
+	ThreadID int64
+}
+
+// SplitTraceRuns splits the trace into the given predictions, which overlap when they run concurrently.
+// An event goes to the prediction of its thread whose window contains its start. An event of another thread,
+// such as one of the thread pools of onnxruntime, goes to the prediction of the only model_run event containing it,
+// or else to the only prediction containing it; the events which can not be told apart are dropped.
+func SplitTraceRuns(t *Trace, runs []TraceRun) []*Trace {
+	tSlice := make([]*Trace, len(runs))
+	for i, run := range runs {
+		tSlice[i] = &Trace{StartTime: time.Unix(0, run.Start)}
+	}
+
+	ownRun := func(event TraceEvent) int {
+		for i, run := range runs {
+			if run.ThreadID == event.ThreadID && event.Start >= run.Start && event.Start <= run.End {
+				return i
+			}
+		}
+		return -1
+	}
+	// onlyRun returns the run of the only window containing the start of the event, -1 if there are none or several
+	onlyRun := func(event TraceEvent, windows []TraceRun, index func(int) int) int {
+		res := -1
+		for i, window := range windows {
+			if event.Start < window.Start || event.Start > window.End {
+				continue
+			}
+			if res != -1 && res != index(i) {
+				return -1
+			}
+			res = index(i)
+		}
+		return res
+	}
+
+	// the model_run events are the windows of the predictions as seen by onnxruntime
+	var modelRuns []TraceRun
+	var modelRunIndex []int
+	for _, event := range t.TraceEvents {
+		if event.Name != "model_run" {
+			continue
+		}
+		if i := ownRun(event); i != -1 {
+			modelRuns = append(modelRuns, TraceRun{Start: event.Start, End: event.End, ThreadID: event.ThreadID})
+			modelRunIndex = append(modelRunIndex, i)
+		}
+	}
+
+	for _, event := range t.TraceEvents {
+		i := ownRun(event)
+		if i == -1 {
+			i = onlyRun(event, modelRuns, func(j int) int { return modelRunIndex[j] })
+		}
+		if i == -1 {
+			i = onlyRun(event, runs, func(j int) int { return j })
+		}
+		if i != -1 {
+			tSlice[i].TraceEvents = append(tSlice[i].TraceEvents, event)
+		}
+	}
+	return tSlice
+}
+
 func NewTrace(data string, start_time int64) (*Trace, error) {
 	trace := new(Trace)
 	err := json.Unmarshal([]byte(data), &trace.TraceEvents)
@@ -0,0 +1,89 @@
+package onnxruntime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTraceInvalid(t *testing.T) {
+	_, err := NewTrace("not a profile", 0)
+	assert.Error(t, err)
+}
+
+func TestSplitTrace(t *testing.T) {
+	trace, err := NewTrace(`[
+		{"name": "a", "ts": 1, "dur": 1},
+		{"name": "b", "ts": 11, "dur": 1},
+		{"name": "late", "ts": 30, "dur": 1}
+	]`, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the trace %v", err)
+	}
+
+	// the times are in nanoseconds, the timestamps of the profile in microseconds
+	traces, err := SplitTrace(trace, []int64{0, 10000}, []int64{5000, 15000})
+	assert.NoError(t, err)
+	if assert.Len(t, traces, 2) {
+		assert.Len(t, traces[0].TraceEvents, 1)
+		assert.Equal(t, "a", traces[0].TraceEvents[0].Name)
+		// the events after the last prediction are dropped
+		assert.Len(t, traces[1].TraceEvents, 1)
+		assert.Equal(t, "b", traces[1].TraceEvents[0].Name)
+	}
+
+	traces, err = SplitTrace(trace, nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, traces)
+
+	_, err = SplitTrace(trace, []int64{0}, nil)
+	assert.Error(t, err)
+}
+
+// TestSplitTraceRuns splits the profile of two predictions running at once on threads 1 and 2
+func TestSplitTraceRuns(t *testing.T) {
+	trace, err := NewTrace(`[
+		{"name": "session_initialization", "ts": 0, "dur": 5, "tid": 1},
+		{"name": "model_run", "ts": 10, "dur": 20, "tid": 1},
+		{"name": "model_run", "ts": 12, "dur": 10, "tid": 2},
+		{"name": "a_kernel_time", "ts": 11, "dur": 2, "tid": 1},
+		{"name": "b_kernel_time", "ts": 13, "dur": 2, "tid": 2},
+		{"name": "c_kernel_time", "ts": 14, "dur": 2, "tid": 1},
+		{"name": "pool", "ts": 25, "dur": 1, "tid": 3},
+		{"name": "ambiguous", "ts": 15, "dur": 1, "tid": 3}
+	]`, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the trace %v", err)
+	}
+
+	// the times are in nanoseconds, the timestamps of the profile in microseconds
+	traces := SplitTraceRuns(trace, []TraceRun{
+		{Start: 9000, End: 31000, ThreadID: 1},
+		{Start: 11000, End: 23000, ThreadID: 2},
+	})
+	names := func(t *Trace) []string {
+		res := []string{}
+		for _, event := range t.TraceEvents {
+			res = append(res, event.Name)
+		}
+		return res
+	}
+	if assert.Len(t, traces, 2) {
+		// pool only falls in the model_run of thread 1, ambiguous falls in both
+		assert.Equal(t, []string{"model_run", "a_kernel_time", "c_kernel_time", "pool"}, names(traces[0]))
+		assert.Equal(t, []string{"model_run", "b_kernel_time"}, names(traces[1]))
+	}
+
+	assert.Empty(t, SplitTraceRuns(trace, nil))
+}
+
+func TestRecoverError(t *testing.T) {
+	fail := func() (err error) {
+		defer recoverError(&err)
+		panic("broken binding")
+	}
+	err := fail()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unexpected panic: broken binding")
+	}
+}
@@ -5,13 +5,12 @@ import "C"
 
 import (
 	"reflect"
-
-	"gorgonia.org/tensor"
 )
 
 /* Description: type conversion between C++ and Golang
  * Reference: https://github.com/microsoft/onnxruntime/blob/master/include/onnxruntime/core/session/onnxruntime_c_api.h
- * Note: Currently, Ort doesn't support complex64, complex128, bfloat16 types
+ * Note: Currently, Ort doesn't support complex64, complex128 types
+ *       float16 and bfloat16 are carried by the Float16 and BFloat16 types defined in float16.go and bfloat16.go
  */
 var types = []struct {
 	typ      reflect.Type
@@ -29,15 +28,26 @@ var types = []struct {
 	{reflect.TypeOf(float64(0)), C.ONNX_TENSOR_ELEMENT_DATA_TYPE_DOUBLE},
 	{reflect.TypeOf(uint32(0)), C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT32},
 	{reflect.TypeOf(uint64(0)), C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT64},
+	{reflect.TypeOf(Float16(0)), C.ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT16},
+	{reflect.TypeOf(BFloat16(0)), C.ONNX_TENSOR_ELEMENT_DATA_TYPE_BFLOAT16},
 	// {reflect.TypeOf(complex64(0)), C.ONNX_TENSOR_ELEMENT_DATA_TYPE_COMPLEX64},
 	// {reflect.TypeOf(complex128(0)), C.ONNX_TENSOR_ELEMENT_DATA_TYPE_COMPLEX128},
 }
 
-func fromType(ten *tensor.Dense) C.ONNXTensorElementDataType {
+func fromType(typ reflect.Type) C.ONNXTensorElementDataType {
 	for _, t := range types {
-		if t.typ == ten.Dtype().Type {
+		if t.typ == typ {
 			return t.dataType
 		}
 	}
 	return C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED
 }
+
+func toType(dataType C.ONNXTensorElementDataType) (reflect.Type, bool) {
+	for _, t := range types {
+		if t.dataType == dataType {
+			return t.typ, true
+		}
+	}
+	return nil, false
+}
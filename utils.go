@@ -1,11 +1,14 @@
 package onnxruntime
 
 // #include "cbits/predictor.hpp"
+// #include <stdlib.h>
 import "C"
 
 import (
+	"reflect"
 	"unsafe"
 
+	"github.com/pkg/errors"
 	"gorgonia.org/tensor"
 )
 
@@ -31,147 +34,323 @@ func getFlattenedLength(data []int64) int {
 	return res
 }
 
+/* Description: Get a C pointer to a shape, scalars have no dimensions and get a null pointer */
+func cShape(shape []int64) *C.int64_t {
+	if len(shape) == 0 {
+		return nil
+	}
+	return (*C.int64_t)(unsafe.Pointer(&shape[0]))
+}
+
+/* Description: Copy the shape of a tensor Ort_Value from C++, the shape_ptr of scalars is null */
+func ortValueShape(ctx C.ORT_Value) []int64 {
+	shapeLength := int(ctx.shape_len)
+	shape := make([]int64, shapeLength)
+	if shapeLength > 0 {
+		copy(shape, (*[1 << 30]int64)(unsafe.Pointer(ctx.shape_ptr))[:shapeLength:shapeLength])
+	}
+	return shape
+}
+
+/* Description: Get a slice of n elements of type typ over memory owned by C++, the memory has to outlive the slice
+ *              An empty slice is returned when there are no elements, ptr may then be null
+ */
+func cSlice(typ reflect.Type, ptr unsafe.Pointer, n int) interface{} {
+	data := reflect.New(reflect.SliceOf(typ))
+	if n > 0 {
+		header := (*reflect.SliceHeader)(unsafe.Pointer(data.Pointer()))
+		header.Data = uintptr(ptr)
+		header.Len = n
+		header.Cap = n
+	} else {
+		data.Elem().Set(reflect.MakeSlice(reflect.SliceOf(typ), 0, 0))
+	}
+	return data.Elem().Interface()
+}
+
+/* Description: Get a byte slice over n bytes of memory, the memory has to outlive the slice */
+func byteSlice(ptr unsafe.Pointer, n int) []byte {
+	return cSlice(reflect.TypeOf(byte(0)), ptr, n).([]byte)
+}
+
+/* Description: Copy the strings into C memory, the result has to be released by freeCStrings */
+func toCStrings(data []string) []*C.char {
+	res := make([]*C.char, len(data))
+	for i, s := range data {
+		res[i] = C.CString(s)
+	}
+	return res
+}
+
+func freeCStrings(cStrings []*C.char) {
+	for _, cs := range cStrings {
+		C.free(unsafe.Pointer(cs))
+	}
+}
+
+/* Description: Get the data of a tensor as a slice, Data() returns a single element for scalar shapes */
+func tensorSlice(t tensor.Tensor) reflect.Value {
+	data := reflect.ValueOf(t.Data())
+	if data.Kind() == reflect.Slice {
+		return data
+	}
+	return reflect.Append(reflect.MakeSlice(reflect.SliceOf(t.Dtype().Type), 0, 1), data)
+}
+
+/* Description: Convert Ort_Value from C++ to a Go value, keeping the structure of sequences and maps
+ *              Tensors become tensor.Tensor, sequences become Sequence and maps become Map
+ */
+func ortValueToGo(ctx C.ORT_Value) (interface{}, error) {
+	elementsLength := int(ctx.elements_len)
+	var elements []C.ORT_Value
+	if elementsLength > 0 {
+		elements = (*[1 << 30]C.ORT_Value)(unsafe.Pointer(ctx.elements_ptr))[:elementsLength:elementsLength]
+	}
+
+	switch ctx.vtype {
+	case C.ONNX_TYPE_TENSOR:
+		return ortValueToTensor(ctx)
+	case C.ONNX_TYPE_SEQUENCE:
+		res := make(Sequence, elementsLength)
+		for i, element := range elements {
+			var err error
+			if res[i], err = ortValueToGo(element); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case C.ONNX_TYPE_MAP:
+		if elementsLength != 2 {
+			return nil, errors.Errorf("invalid map value with %d elements", elementsLength)
+		}
+		keys, err := ortValueToTensor(elements[0])
+		if err != nil {
+			return nil, err
+		}
+		values, err := ortValueToTensor(elements[1])
+		if err != nil {
+			return nil, err
+		}
+		return Map{
+			Keys:   keys,
+			Values: values,
+		}, nil
+	default:
+		return nil, errors.Errorf("invalid value type %d", int(ctx.vtype))
+	}
+}
+
+/* Description: Convert Ort_Value from C++ converted as a view to a Go value
+ *              Numeric tensors view the memory owned by onnxruntime, everything else is copied
+ */
+func ortValueViewToGo(ctx C.ORT_Value) (interface{}, error) {
+	if ctx.vtype == C.ONNX_TYPE_TENSOR && ctx.otype != C.ONNX_TENSOR_ELEMENT_DATA_TYPE_STRING {
+		return ortValueViewToTensor(ctx)
+	}
+	return ortValueToGo(ctx)
+}
+
+/* Description: Convert a numeric tensor from C++ to a Go tensor whose backing slice is the C++ memory */
+func ortValueViewToTensor(ctx C.ORT_Value) (tensor.Tensor, error) {
+	v, err := ortValueViewToValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return valueToTensor(v), nil
+}
+
+/* Description: Convert a numeric tensor from C++ to a Value whose slice is the C++ memory */
+func ortValueViewToValue(ctx C.ORT_Value) (Value, error) {
+	shape := ortValueShape(ctx)
+	flattenedLength := getFlattenedLength(shape)
+
+	typ, ok := toType(ctx.otype)
+	if !ok || ctx.otype == C.ONNX_TENSOR_ELEMENT_DATA_TYPE_STRING {
+		return Value{}, errors.Errorf("invalid data type %d", int(ctx.otype))
+	}
+
+	return Value{Data: cSlice(typ, ctx.data_ptr, flattenedLength), Shape: shape}, nil
+}
+
+/* Description: Convert Ort_Value from C++ to Values, sequences and maps are flattened into their tensors in order */
+func ortValueToValues(ctx C.ORT_Value) ([]Value, error) {
+	switch ctx.vtype {
+	case C.ONNX_TYPE_TENSOR:
+		v, err := ortValueToValue(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []Value{v}, nil
+	case C.ONNX_TYPE_SEQUENCE, C.ONNX_TYPE_MAP:
+		elementsLength := int(ctx.elements_len)
+		res := []Value{}
+		if elementsLength == 0 {
+			return res, nil
+		}
+		elements := (*[1 << 30]C.ORT_Value)(unsafe.Pointer(ctx.elements_ptr))[:elementsLength:elementsLength]
+		for _, element := range elements {
+			values, err := ortValueToValues(element)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, values...)
+		}
+		return res, nil
+	default:
+		return nil, errors.Errorf("invalid value type %d", int(ctx.vtype))
+	}
+}
+
+/* Description: Flatten sequences and maps into their tensors, in order */
+func flattenValue(value interface{}) []tensor.Tensor {
+	switch v := value.(type) {
+	case tensor.Tensor:
+		return []tensor.Tensor{v}
+	case Sequence:
+		res := []tensor.Tensor{}
+		for _, element := range v {
+			res = append(res, flattenValue(element)...)
+		}
+		return res
+	case Map:
+		return []tensor.Tensor{v.Keys, v.Values}
+	default:
+		return nil
+	}
+}
+
 /* Description: Convert Ort_Value from C++ to Go tensor, referenced from ivalueToTensor in go-pytorch
  * Referenced: https://github.com/c3sr/go-pytorch/blob/master/utils.go
  */
-func ortValueToTensor(ctx C.ORT_Value) tensor.Tensor {
-	shapeLength := int64(ctx.shape_len)
+func ortValueToTensor(ctx C.ORT_Value) (tensor.Tensor, error) {
+	v, err := ortValueToValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return valueToTensor(v), nil
+}
+
+/* Description: Convert a tensor Ort_Value from C++ to a Value, copying its data into Go memory */
+func ortValueToValue(ctx C.ORT_Value) (Value, error) {
 	ptr := ctx.data_ptr
-	cShape := ctx.shape_ptr
 	ty := ctx.otype
 
-	cShapeSlice := (*[1 << 30]int64)(unsafe.Pointer(cShape))[:shapeLength:shapeLength]
+	shape := ortValueShape(ctx)
+	flattenedLength := getFlattenedLength(shape)
 
-	shape := tensor.Shape(toIntSlice(cShapeSlice))
-	flattenedLength := getFlattenedLength(cShapeSlice)
+	// zero-element tensors have no data in C
+	if typ, ok := toType(ty); ok && flattenedLength == 0 {
+		return Value{Data: reflect.MakeSlice(reflect.SliceOf(typ), 0, 0).Interface(), Shape: shape}, nil
+	}
 
 	switch ty {
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UNDEFINED:
 		{
-			panic("undefined data type!")
+			return Value{}, errors.New("undefined data type")
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT:
 		{
 			cData := (*[1 << 30]float32)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]float32, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Float32,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT8:
 		{
 			cData := (*[1 << 30]uint8)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]uint8, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Uint8,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_INT8:
 		{
 			cData := (*[1 << 30]int8)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]int8, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Int8,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT16:
 		{
 			cData := (*[1 << 30]uint16)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]uint16, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Uint16,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_INT16:
 		{
 			cData := (*[1 << 30]int16)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]int16, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Int16,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_INT32:
 		{
 			cData := (*[1 << 30]int32)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]int32, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Int32,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_INT64:
 		{
 			cData := (*[1 << 30]int64)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]int64, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Int64,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_BOOL:
 		{
 			cData := (*[1 << 30]bool)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]bool, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Bool,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_DOUBLE:
 		{
 			cData := (*[1 << 30]float64)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]float64, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Float64,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT32:
 		{
 			cData := (*[1 << 30]uint32)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]uint32, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Uint32,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
 		}
 	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_UINT64:
 		{
 			cData := (*[1 << 30]uint64)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
 			data := make([]uint64, flattenedLength)
 			copy(data, cData)
-			return tensor.NewDense(
-				tensor.Uint64,
-				shape,
-				tensor.WithBacking(data),
-			)
+			return Value{Data: data, Shape: shape}, nil
+		}
+	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT16:
+		{
+			cData := (*[1 << 30]Float16)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
+			data := make([]Float16, flattenedLength)
+			copy(data, cData)
+			return Value{Data: data, Shape: shape}, nil
+		}
+	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_BFLOAT16:
+		{
+			cData := (*[1 << 30]BFloat16)(unsafe.Pointer(ptr))[:flattenedLength:flattenedLength]
+			data := make([]BFloat16, flattenedLength)
+			copy(data, cData)
+			return Value{Data: data, Shape: shape}, nil
+		}
+	case C.ONNX_TENSOR_ELEMENT_DATA_TYPE_STRING:
+		{
+			offsetsLength := flattenedLength + 1
+			cOffsets := (*[1 << 30]C.size_t)(unsafe.Pointer(ctx.offsets_ptr))[:offsetsLength:offsetsLength]
+			data := make([]string, flattenedLength)
+			for i := range data {
+				start := unsafe.Pointer(uintptr(unsafe.Pointer(ptr)) + uintptr(cOffsets[i]))
+				data[i] = C.GoStringN((*C.char)(start), C.int(cOffsets[i+1]-cOffsets[i]))
+			}
+			return Value{Data: data, Shape: shape}, nil
 		}
 	default:
-		panic("invalid data type")
+		return Value{}, errors.Errorf("invalid data type %d", int(ty))
 	}
 }
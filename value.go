@@ -0,0 +1,131 @@
+package onnxruntime
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// Value is a tensor held in a plain Go slice, for callers that do not want to depend on gorgonia.
+// Data is a slice of one of the supported element types ([]float32, []int64, []string, []Float16, ...)
+// holding the elements in row major order, and Shape holds the dimensions of the tensor.
+type Value struct {
+	Data  interface{}
+	Shape []int64
+}
+
+// Len returns the number of elements in Data
+func (v Value) Len() int {
+	data := reflect.ValueOf(v.Data)
+	if data.Kind() != reflect.Slice {
+		return 0
+	}
+	return data.Len()
+}
+
+// check verifies that Data is a slice holding as many elements as Shape describes
+func (v Value) check() error {
+	data := reflect.ValueOf(v.Data)
+	if data.Kind() != reflect.Slice {
+		return errors.Errorf("value data of type %T is not a slice", v.Data)
+	}
+	for _, d := range v.Shape {
+		if d < 0 {
+			return errors.Errorf("negative dimension in shape %v", v.Shape)
+		}
+	}
+	if n := getFlattenedLength(v.Shape); data.Len() != n {
+		return errors.Errorf("value holds %d elements but its shape %v needs %d", data.Len(), v.Shape, n)
+	}
+	return nil
+}
+
+/* Description: Get the Value of an input given either as a Value or as a gorgonia tensor */
+func toValue(input interface{}) (Value, error) {
+	switch in := input.(type) {
+	case Value:
+		return in, nil
+	case tensor.Tensor:
+		return tensorToValue(in)
+	default:
+		return Value{}, errors.Errorf("unsupported tensor of type %T", input)
+	}
+}
+
+/* Description: Convert a gorgonia tensor to a Value
+ *              Contiguous row major dense tensors share their backing slice with the value,
+ *              views, transposed and other tensor implementations are materialized into a new slice
+ *              Scalars are returned as a one element slice and zero-element tensors as an empty slice
+ */
+func tensorToValue(t tensor.Tensor) (Value, error) {
+	shape := make([]int64, len(t.Shape()))
+	for i, s := range t.Shape() {
+		shape[i] = int64(s)
+	}
+
+	if getFlattenedLength(shape) == 0 {
+		return Value{Data: reflect.MakeSlice(reflect.SliceOf(t.Dtype().Type), 0, 0).Interface(), Shape: shape}, nil
+	}
+
+	if dense, ok := t.(*tensor.Dense); ok {
+		if isRowMajor(dense) {
+			return Value{Data: tensorSlice(dense).Interface(), Shape: shape}, nil
+		}
+		if dense.IsMaterializable() {
+			if m, ok := dense.Materialize().(*tensor.Dense); ok && isRowMajor(m) {
+				return Value{Data: tensorSlice(m).Interface(), Shape: shape}, nil
+			}
+		}
+	}
+
+	data, err := materialize(t, shape)
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{Data: data.Interface(), Shape: shape}, nil
+}
+
+/* Description: Check whether the backing slice of a dense tensor holds exactly its elements in row major order */
+func isRowMajor(dense *tensor.Dense) bool {
+	for _, d := range dense.Shape() {
+		if d == 0 {
+			// gorgonia reports that empty tensors require an iterator, but they have no elements to order
+			return true
+		}
+	}
+	return !dense.RequiresIterator() && !dense.DataOrder().IsColMajor()
+}
+
+/* Description: Copy the elements of any tensor implementation into a new slice in row major order */
+func materialize(t tensor.Tensor, shape []int64) (reflect.Value, error) {
+	n := getFlattenedLength(shape)
+	data := reflect.MakeSlice(reflect.SliceOf(t.Dtype().Type), n, n)
+
+	coords := make([]int, len(shape))
+	for i := 0; i < n; i++ {
+		elem, err := t.At(coords...)
+		if err != nil {
+			return reflect.Value{}, errors.Wrapf(err, "failed to read the tensor at %v", coords)
+		}
+		data.Index(i).Set(reflect.ValueOf(elem))
+
+		for d := len(coords) - 1; d >= 0; d-- {
+			coords[d]++
+			if int64(coords[d]) < shape[d] {
+				break
+			}
+			coords[d] = 0
+		}
+	}
+	return data, nil
+}
+
+/* Description: Wrap a Value into a gorgonia tensor, the tensor shares the slice of the value */
+func valueToTensor(v Value) tensor.Tensor {
+	return tensor.NewDense(
+		tensor.Dtype{Type: reflect.TypeOf(v.Data).Elem()},
+		tensor.Shape(toIntSlice(v.Shape)),
+		tensor.WithBacking(v.Data),
+	)
+}
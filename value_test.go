@@ -0,0 +1,120 @@
+package onnxruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+// index is a gorgonia slice selecting a single index of a dimension
+type index int
+
+func (i index) Start() int { return int(i) }
+func (i index) End() int   { return int(i) + 1 }
+func (i index) Step() int  { return 0 }
+
+func TestTensorToValueSharesDenseBacking(t *testing.T) {
+	data := []float32{1, 2, 3, 4, 5, 6}
+	ten := gotensor.New(gotensor.WithBacking(data), gotensor.WithShape(2, 3))
+
+	v, err := tensorToValue(ten)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, v.Shape)
+	assert.NoError(t, v.check())
+
+	data[0] = 10
+	assert.Equal(t, float32(10), v.Data.([]float32)[0])
+}
+
+func TestTensorToValueMaterializes(t *testing.T) {
+	ten := gotensor.New(gotensor.WithBacking([]int64{1, 2, 3, 4, 5, 6}), gotensor.WithShape(2, 3))
+
+	col, err := ten.Slice(nil, index(1))
+	assert.NoError(t, err)
+	v, err := tensorToValue(col)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, v.Shape)
+	assert.Equal(t, []int64{2, 5}, v.Data)
+
+	transposed := ten.Clone().(*gotensor.Dense)
+	assert.NoError(t, transposed.T())
+	v, err = tensorToValue(transposed)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 2}, v.Shape)
+	assert.Equal(t, []int64{1, 4, 2, 5, 3, 6}, v.Data)
+
+	sparse := gotensor.CSCFromCoord(gotensor.Shape{2, 2}, []int{0, 1}, []int{1, 0}, []float64{7, 8})
+	v, err = tensorToValue(sparse)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 2}, v.Shape)
+	assert.Equal(t, []float64{0, 7, 8, 0}, v.Data)
+}
+
+func TestValueCheck(t *testing.T) {
+	assert.NoError(t, Value{Data: []float32{1, 2, 3, 4}, Shape: []int64{2, 2}}.check())
+	assert.Error(t, Value{Data: []float32{1, 2, 3}, Shape: []int64{2, 2}}.check())
+	assert.Error(t, Value{Data: float32(1), Shape: []int64{1}}.check())
+	assert.Error(t, Value{Data: []float32{}, Shape: []int64{-1}}.check())
+}
+
+func TestPredictSlices(t *testing.T) {
+	ctx := context.Background()
+
+	predictor := newCPUPredictor(t, castModel(t, onnxInt32, onnxDouble))
+	defer predictor.Close()
+
+	result, err := predictor.PredictSlices(ctx, []Value{{Data: []int32{1, -2, 3}, Shape: []int64{3}}})
+	if err != nil {
+		t.Errorf("Onnxruntime predictor predicting failed %v", err)
+		return
+	}
+	defer result.Close()
+
+	outputs, err := result.ReadPredictionOutputSlices(ctx)
+	if err != nil {
+		t.Errorf("Onnxruntime predictor read prediction output failed %v", err)
+		return
+	}
+	assert.Equal(t, []Value{{Data: []float64{1, -2, 3}, Shape: []int64{3}}}, outputs)
+
+	_, err = predictor.PredictSlices(ctx, []Value{{Data: []complex64{1}, Shape: []int64{1}}})
+	assert.Error(t, err)
+}
+
+func TestPredictTensorView(t *testing.T) {
+	ten := gotensor.New(gotensor.WithBacking([]float32{1, 2, 3, 4, 5, 6}), gotensor.WithShape(3, 2))
+	col, err := ten.Slice(nil, index(0))
+	assert.NoError(t, err)
+
+	predictor := newCPUPredictor(t, identityModel(t, onnxFloat))
+	defer predictor.Close()
+
+	outputs := runTestPredictor(t, predictor, col)
+	assert.Equal(t, gotensor.Shape{3}, outputs[0].Shape())
+	assert.Equal(t, []float32{1, 3, 5}, outputs[0].Data().([]float32))
+}
+
+func TestPredictCopiesInputs(t *testing.T) {
+	ctx := context.Background()
+
+	predictor := newCPUPredictor(t, identityModel(t, onnxFloat))
+	defer predictor.Close()
+
+	data := []float32{1, 2, 3}
+	result, err := predictor.PredictSlices(ctx, []Value{{Data: data, Shape: []int64{3}}})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	// the input belongs to the caller again once Predict returns
+	data[0] = 10
+
+	outputs, err := result.ReadPredictionOutputSlices(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output failed %v", err)
+	}
+	assert.Equal(t, []float32{1, 2, 3}, outputs[0].Data)
+}
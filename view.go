@@ -0,0 +1,100 @@
+package onnxruntime
+
+// #include "cbits/predictor.hpp"
+import "C"
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/c3sr/tracer"
+	"github.com/pkg/errors"
+	"gorgonia.org/tensor"
+)
+
+// OutputView holds the outputs of a prediction without copying them out of onnxruntime:
+// the backing slices of numeric tensors are the memory of the onnxruntime outputs.
+// The tensors are valid until Release is called, which has to be done explicitly: the view has no finalizer,
+// since the tensors and the values do not keep it reachable and a collected view would free their memory.
+// String tensors and the elements of sequences and maps are still copied.
+type OutputView struct {
+	// Values holds one value per output of the model, as returned by ReadPredictionOutputValues
+	Values  []interface{}
+	handles []C.ORT_ValueContext
+}
+
+// ReadPredictionOutputView returns the outputs of the prediction as a view,
+// the outputs are moved out of the result so they can only be read once
+func (r *Result) ReadPredictionOutputView(ctx context.Context) (_ *OutputView, err error) {
+	defer recoverError(&err)
+
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+
+	span, _ := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_read_predicted_output")
+	defer span.Finish()
+	defer r.session.recordSpan("c_read_predicted_output", time.Now())
+
+	defer runtime.KeepAlive(r)
+
+	var cErr C.ORT_Error
+	cNumOutputs := int(C.ORT_RunNumOutputValues(r.ctx, &cErr))
+	if err := takeError(&cErr); err != nil {
+		return nil, err
+	}
+
+	if cNumOutputs == 0 {
+		return nil, errors.New("zero number of outputs")
+	}
+
+	view := &OutputView{}
+
+	for i := 0; i < cNumOutputs; i++ {
+		handle := C.ORT_RunTakeOutput(r.ctx, C.int(i), &cErr)
+		if err := takeError(&cErr); err != nil {
+			view.Release()
+			return nil, err
+		}
+		view.handles = append(view.handles, handle)
+
+		cValue := C.ORT_ValueConvert(handle, true, &cErr)
+		if err := takeError(&cErr); err != nil {
+			view.Release()
+			return nil, err
+		}
+		value, err := ortValueViewToGo(cValue)
+		C.ORT_ValueFree(cValue, true, &cErr)
+		if freeErr := takeError(&cErr); err == nil {
+			err = freeErr
+		}
+		if err != nil {
+			view.Release()
+			return nil, err
+		}
+		view.Values = append(view.Values, value)
+	}
+
+	return view, nil
+}
+
+// Tensors returns the outputs as tensors, sequences and maps are flattened into the tensors they contain
+func (v *OutputView) Tensors() []tensor.Tensor {
+	res := []tensor.Tensor{}
+	for _, value := range v.Values {
+		res = append(res, flattenValue(value)...)
+	}
+	return res
+}
+
+// Release gives the memory of the outputs back to onnxruntime, the tensors of the view must not be used afterwards
+func (v *OutputView) Release() {
+	if v == nil {
+		return
+	}
+	for _, handle := range v.handles {
+		deleteValue(handle)
+	}
+	v.handles = nil
+	v.Values = nil
+}
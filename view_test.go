@@ -0,0 +1,149 @@
+package onnxruntime
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestReadPredictionOutputView(t *testing.T) {
+	predictor := newCPUPredictor(t, identityModel(t, onnxFloat))
+	defer predictor.Close()
+
+	data := []float32{1, 2, 3, 4, 5, 6}
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(data), gotensor.WithShape(len(data))),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	view, err := result.ReadPredictionOutputView(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output view failed %v", err)
+	}
+
+	tensors := view.Tensors()
+	assert.Equal(t, 1, len(tensors))
+	assert.Equal(t, gotensor.Shape{len(data)}, tensors[0].Shape())
+	assert.Equal(t, data, tensors[0].Data().([]float32))
+
+	// the outputs have been moved into the view, reading them again is a misuse
+	_, err = result.ReadPredictionOutput(ctx)
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "unexpected error %v", err)
+	_, err = result.ReadPredictionOutputView(ctx)
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "unexpected error %v", err)
+
+	view.Release()
+	assert.Nil(t, view.Values)
+	view.Release()
+}
+
+func TestReadPredictionOutputViewCollected(t *testing.T) {
+	predictor := newCPUPredictor(t, identityModel(t, onnxFloat))
+	defer predictor.Close()
+
+	data := []float32{1, 2, 3, 4, 5, 6}
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking(data), gotensor.WithShape(len(data))),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	view, err := result.ReadPredictionOutputView(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output view failed %v", err)
+	}
+	result.Close()
+
+	// only the tensors are held, the collection of the view must not free their memory
+	tensors := view.Tensors()
+	handles := view.handles
+	view = nil
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+	}
+	assert.Equal(t, data, tensors[0].Data().([]float32))
+
+	for _, handle := range handles {
+		deleteValue(handle)
+	}
+}
+
+func TestReadPredictionOutputViewStrings(t *testing.T) {
+	predictor := newCPUPredictor(t, identityModel(t, onnxString))
+	defer predictor.Close()
+
+	data := []string{"a", "", "ünïcode"}
+	ctx := context.Background()
+	result, err := predictor.Predict(ctx, []gotensor.Tensor{
+		gotensor.New(gotensor.Of(gotensor.String), gotensor.WithBacking(data), gotensor.WithShape(len(data))),
+	})
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor predicting failed %v", err)
+	}
+	defer result.Close()
+
+	view, err := result.ReadPredictionOutputView(ctx)
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor read prediction output view failed %v", err)
+	}
+	defer view.Release()
+
+	assert.Equal(t, data, view.Tensors()[0].Data().([]string))
+}
+
+// the size of a 1024x1024 RGB segmentation mask
+const benchmarkOutputSize = 3 * 1024 * 1024
+
+func benchmarkReadPredictionOutput(b *testing.B, view bool) {
+	predictor := newCPUPredictor(b, identityModel(b, onnxFloat))
+	defer predictor.Close()
+
+	size := benchmarkOutputSize
+	input := gotensor.New(
+		gotensor.Of(gotensor.Float32),
+		gotensor.WithBacking(make([]float32, size)),
+		gotensor.WithShape(size),
+	)
+
+	ctx := context.Background()
+	b.SetBytes(int64(size * 4))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := predictor.Predict(ctx, []gotensor.Tensor{input})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if view {
+			outputs, err := result.ReadPredictionOutputView(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			outputs.Release()
+		} else {
+			if _, err := result.ReadPredictionOutput(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+		result.Close()
+	}
+}
+
+// BenchmarkReadPredictionOutput copies every output twice, once in C++ and once in Go
+func BenchmarkReadPredictionOutput(b *testing.B) {
+	benchmarkReadPredictionOutput(b, false)
+}
+
+// BenchmarkReadPredictionOutputView does not copy the outputs
+func BenchmarkReadPredictionOutputView(b *testing.B) {
+	benchmarkReadPredictionOutput(b, true)
+}
@@ -0,0 +1,211 @@
+package onnxruntime
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/c3sr/dlframework/framework/options"
+	"github.com/pkg/errors"
+)
+
+// WatchEvent reports a change of the model file seen by a Watcher
+type WatchEvent struct {
+	Path     string
+	Checksum string
+	// Err is nil when the predictor was reloaded, otherwise the predictor keeps its previous model
+	Err error
+}
+
+type watchOptions struct {
+	interval time.Duration
+	debounce time.Duration
+	callback func(WatchEvent)
+}
+
+// WatchOption configures Predictor.Watch
+type WatchOption func(*watchOptions)
+
+// WatchInterval sets how often the model file is checked, every second by default
+func WatchInterval(interval time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.interval = interval
+	}
+}
+
+// WatchDebounce sets how long the model file has to stay unchanged before it is loaded,
+// so that a file being copied is not loaded halfway. It is 2 seconds by default.
+func WatchDebounce(debounce time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.debounce = debounce
+	}
+}
+
+// WatchCallback sets a function called after every attempt to reload the model, from the goroutine of the watcher
+func WatchCallback(callback func(WatchEvent)) WatchOption {
+	return func(o *watchOptions) {
+		o.callback = callback
+	}
+}
+
+// Watcher reloads a predictor when its model file changes
+type Watcher struct {
+	predictor *Predictor
+	path      string
+	opts      watchOptions
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+
+	// the state of the file when it was last checked, and since when it has not changed
+	modTime time.Time
+	size    int64
+	stable  time.Time
+	pending bool
+	// checksum is the checksum of the last file loaded or tried, empty when the file changed since it was loaded
+	checksum string
+}
+
+// Watch polls the model file given by options.Graph, and reloads the predictor with Reload once the file has changed
+// and stayed unchanged for the debounce delay, including the changes made since the model was loaded.
+// Changes to the modification time or the size which leave the content untouched are ignored. When the new file can not be loaded, the predictor keeps serving the previous model.
+// The watcher stops when ctx is done, when it is closed or when the predictor is closed.
+func (p *Predictor) Watch(ctx context.Context, opts ...WatchOption) (*Watcher, error) {
+	s, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	path := string(s.options.Graph())
+	modTime, size := s.modelModTime, s.modelSize
+	s.release()
+
+	w := &Watcher{
+		predictor: p,
+		path:      path,
+		opts: watchOptions{
+			interval: time.Second,
+			debounce: 2 * time.Second,
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(&w.opts)
+	}
+	if w.opts.interval <= 0 {
+		return nil, errors.Errorf("invalid watch interval %v", w.opts.interval)
+	}
+
+	// the baseline is the file as it was loaded, a change made since then is reloaded by the first polls
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to watch %s", path)
+	}
+	w.modTime, w.size = modTime, size
+	if info.ModTime().Equal(modTime) && info.Size() == size {
+		if w.checksum, err = fileChecksum(path); err != nil {
+			return nil, errors.Wrapf(err, "failed to watch %s", path)
+		}
+	}
+
+	go w.run(ctx)
+
+	return w, nil
+}
+
+func (w *Watcher) run(ctx context.Context) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.opts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case now := <-ticker.C:
+			if !w.poll(ctx, now) {
+				return
+			}
+		}
+	}
+}
+
+// poll checks the model file and reloads it once its changes have settled,
+// it returns false once the predictor is closed and there is nothing left to reload
+func (w *Watcher) poll(ctx context.Context, now time.Time) bool {
+	if w.predictor.closed() {
+		return false
+	}
+	info, err := os.Stat(w.path)
+	if err != nil {
+		// the file may be in the middle of being replaced
+		return true
+	}
+	if !info.ModTime().Equal(w.modTime) || info.Size() != w.size {
+		w.modTime, w.size = info.ModTime(), info.Size()
+		w.stable = now
+		w.pending = true
+		return true
+	}
+	if !w.pending || now.Sub(w.stable) < w.opts.debounce {
+		return true
+	}
+	w.pending = false
+
+	checksum, err := fileChecksum(w.path)
+	if err != nil {
+		w.notify(WatchEvent{Path: w.path, Err: err})
+		return true
+	}
+	if checksum == w.checksum {
+		return true
+	}
+	// a file failing to load is not tried again until it changes
+	w.checksum = checksum
+
+	err = w.predictor.Reload(ctx, options.Graph([]byte(w.path)))
+	// the predictor may be closed while reloading
+	if err != nil && w.predictor.closed() {
+		return false
+	}
+	w.notify(WatchEvent{Path: w.path, Checksum: checksum, Err: err})
+	return true
+}
+
+func (w *Watcher) notify(event WatchEvent) {
+	if w.opts.callback != nil {
+		w.opts.callback(event)
+	}
+}
+
+// Close stops the watcher, waiting for a reload in progress
+func (w *Watcher) Close() {
+	if w == nil {
+		return
+	}
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
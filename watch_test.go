@@ -0,0 +1,137 @@
+package onnxruntime
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	gotensor "gorgonia.org/tensor"
+)
+
+// replaceFile writes data to path with a later modification time,
+// so that the watcher sees the change even when the size and the time stay the same
+func replaceFile(t *testing.T, path string, data []byte) {
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat %s %v", path, err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write %s %v", path, err)
+	}
+	modTime := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to change the time of %s %v", path, err)
+	}
+}
+
+func waitWatchEvent(t *testing.T, events chan WatchEvent) WatchEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Onnxruntime predictor watcher did not reload the model")
+		return WatchEvent{}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := doubleModel(t)
+	square, err := ioutil.ReadFile(squareModel(t))
+	if err != nil {
+		t.Fatalf("failed to read the model %v", err)
+	}
+
+	predictor := newCPUPredictor(t, path)
+	defer predictor.Close()
+
+	events := make(chan WatchEvent, 10)
+	watcher, err := predictor.Watch(context.Background(),
+		WatchInterval(10*time.Millisecond),
+		WatchDebounce(50*time.Millisecond),
+		WatchCallback(func(event WatchEvent) {
+			events <- event
+		}))
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor watch failed %v", err)
+	}
+	defer watcher.Close()
+
+	input := gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4))
+
+	replaceFile(t, path, square)
+	event := waitWatchEvent(t, events)
+	assert.NoError(t, event.Err)
+	assert.Equal(t, path, event.Path)
+	outputs := runTestPredictor(t, predictor, input)
+	assert.Equal(t, []float32{1, 4, 9, 16}, outputs[0].Data())
+
+	// a broken file is reported and the predictor keeps the model it had
+	replaceFile(t, path, []byte("not a model"))
+	event = waitWatchEvent(t, events)
+	assert.Error(t, event.Err)
+	outputs = runTestPredictor(t, predictor, input)
+	assert.Equal(t, []float32{1, 4, 9, 16}, outputs[0].Data())
+
+	watcher.Close()
+	watcher.Close()
+}
+
+func TestWatchChangedSinceLoad(t *testing.T) {
+	path := doubleModel(t)
+	square, err := ioutil.ReadFile(squareModel(t))
+	if err != nil {
+		t.Fatalf("failed to read the model %v", err)
+	}
+
+	predictor := newCPUPredictor(t, path)
+	defer predictor.Close()
+
+	// the file changes between the load and the watch, the watcher still reloads it
+	replaceFile(t, path, square)
+
+	events := make(chan WatchEvent, 10)
+	watcher, err := predictor.Watch(context.Background(),
+		WatchInterval(10*time.Millisecond),
+		WatchDebounce(50*time.Millisecond),
+		WatchCallback(func(event WatchEvent) {
+			events <- event
+		}))
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor watch failed %v", err)
+	}
+	defer watcher.Close()
+
+	event := waitWatchEvent(t, events)
+	assert.NoError(t, event.Err)
+	input := gotensor.New(gotensor.Of(gotensor.Float32), gotensor.WithBacking([]float32{1, 2, 3, 4}), gotensor.WithShape(4))
+	outputs := runTestPredictor(t, predictor, input)
+	assert.Equal(t, []float32{1, 4, 9, 16}, outputs[0].Data())
+}
+
+func TestWatchPredictorClosed(t *testing.T) {
+	predictor := newCPUPredictor(t, doubleModel(t))
+
+	events := make(chan WatchEvent, 10)
+	watcher, err := predictor.Watch(context.Background(),
+		WatchInterval(10*time.Millisecond),
+		WatchDebounce(50*time.Millisecond),
+		WatchCallback(func(event WatchEvent) {
+			events <- event
+		}))
+	if err != nil {
+		t.Fatalf("Onnxruntime predictor watch failed %v", err)
+	}
+	defer watcher.Close()
+
+	// the watcher stops by itself once there is no predictor to reload
+	predictor.Close()
+	select {
+	case <-watcher.done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Onnxruntime predictor watcher did not stop")
+	}
+	assert.Empty(t, events)
+}